@@ -3,7 +3,10 @@ set -e
 
 echo "Starting Docker daemon..."
 # Docker daemonをバックグラウンドで起動
-dockerd-entrypoint.sh &
+# イメージ pull の並列度とリトライ回数（不安定な回線向け。中断したレイヤーは続きから再取得される）
+dockerd-entrypoint.sh \
+    --max-concurrent-downloads="${DOCKER_MAX_CONCURRENT_DOWNLOADS:-3}" \
+    --max-download-attempts="${DOCKER_MAX_DOWNLOAD_ATTEMPTS:-5}" &
 
 # Docker daemonの起動を待機
 timeout=${DOCKER_DAEMON_TIMEOUT:-60}
@@ -10,6 +10,7 @@ import logging
 import os
 import sys
 
+from .log_exporter import BatchingLogHandler, create_log_sink_from_env, log_group_name
 from .logging_config import CustomJsonFormatter, VictoriaLogsHandler
 
 
@@ -37,7 +38,9 @@ def robust_lambda_logger(service_name: str = "lambda"):
     Decorator for Lambda handlers to ensure logs are flushed and stdout is captured.
 
     Features:
-    - Adds VictoriaLogsHandler if VICTORIALOGS_URL is set
+    - Adds BatchingLogHandler if LOG_SINK is set (log group: LOG_GROUP_PATTERN applied to
+      AWS_LAMBDA_FUNCTION_NAME, falling back to context.function_name / service_name)
+    - Otherwise adds VictoriaLogsHandler if VICTORIALOGS_URL is set
     - Captures stdout/stderr and sends through logging
     - Flushes all handlers in finally block (important for Lambda freeze)
 
@@ -57,8 +60,22 @@ def robust_lambda_logger(service_name: str = "lambda"):
 
             logger = logging.getLogger()
 
+            # セットアップ: LOG_SINK 指定時は関数のロググループへバッチ送信
+            if not any(isinstance(h, BatchingLogHandler) for h in logger.handlers):
+                sink = create_log_sink_from_env()
+                if sink:
+                    function_name = (
+                        os.getenv("AWS_LAMBDA_FUNCTION_NAME")
+                        or getattr(context, "function_name", None)
+                        or service_name
+                    )
+                    handler = BatchingLogHandler(sink, log_group=log_group_name(function_name))
+                    handler.setFormatter(CustomJsonFormatter())
+                    logger.addHandler(handler)
+            use_exporter = any(isinstance(h, BatchingLogHandler) for h in logger.handlers)
+
             # セットアップ: VictoriaLogsHandlerが存在しなければ追加
-            if vl_url:
+            if vl_url and not use_exporter:
                 # 既存ハンドラーチェック（重複防止）
                 if not any(isinstance(h, VictoriaLogsHandler) for h in logger.handlers):
                     handler = VictoriaLogsHandler(
@@ -72,6 +89,7 @@ def robust_lambda_logger(service_name: str = "lambda"):
                     if logger.getEffectiveLevel() > logging.INFO:
                         logger.setLevel(logging.INFO)
 
+            if vl_url or use_exporter:
                 # 標準出力のハイジャック
                 sys.stdout = StreamToLogger(logging.getLogger("stdout"), logging.INFO)
                 sys.stderr = StreamToLogger(logging.getLogger("stderr"), logging.ERROR)
@@ -84,7 +102,7 @@ def robust_lambda_logger(service_name: str = "lambda"):
                 # 同期ハンドラーであっても、明示的にflushを呼ぶ習慣をつける
                 # (将来的にバッファリングを入れた場合への備え)
                 for h in logger.handlers:
-                    if isinstance(h, VictoriaLogsHandler):
+                    if isinstance(h, (VictoriaLogsHandler, BatchingLogHandler)):
                         h.flush()
 
                 sys.stdout = original_stdout
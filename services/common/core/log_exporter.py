@@ -0,0 +1,313 @@
+"""
+Log Exporter
+Batched log shipping to a pluggable sink.
+
+Provides:
+- LogSink implementations: VictoriaLogs / Loki push / CloudWatch Logs / local file
+- BatchingLogHandler: Buffers records and ships them in batches from a background thread.
+  Keeps (bounded) records while the uplink is down and retries with backoff.
+- create_log_sink_from_env: Sink selection via LOG_SINK / LOG_SINK_URL
+"""
+
+import itertools
+import json
+import logging
+import os
+import re
+import socket
+import sys
+import threading
+import time
+import urllib.parse
+import urllib.request
+from collections import deque
+from datetime import datetime
+from typing import Any, Deque, Dict, List, Optional
+
+DEFAULT_LOG_GROUP_PATTERN = "/aws/lambda/{function_name}"
+
+
+def log_group_name(function_name: str, pattern: Optional[str] = None) -> str:
+    """関数名からロググループ名を生成 (LOG_GROUP_PATTERN で変更可能)"""
+    pattern = pattern or os.environ.get("LOG_GROUP_PATTERN", DEFAULT_LOG_GROUP_PATTERN)
+    return pattern.format(function_name=function_name)
+
+
+def _timestamp_ms(entry: Dict[str, Any]) -> int:
+    """エントリの _time (ISO8601) をエポックミリ秒に変換"""
+    try:
+        return int(datetime.fromisoformat(entry["_time"]).timestamp() * 1000)
+    except (KeyError, TypeError, ValueError):
+        return int(time.time() * 1000)
+
+
+class LogSink:
+    """ログ送信先の基底クラス。送信失敗時は例外を送出する"""
+
+    def send(self, log_group: str, entries: List[Dict[str, Any]]) -> None:
+        raise NotImplementedError
+
+
+class VictoriaLogsSink(LogSink):
+    """VictoriaLogs の jsonline API へ送信"""
+
+    def __init__(self, url: str, timeout: float = 2.0):
+        self.url = url
+        self.timeout = timeout
+
+    def send(self, log_group: str, entries: List[Dict[str, Any]]) -> None:
+        params = urllib.parse.urlencode(
+            {
+                "_stream_fields": "log_group",
+                "_msg_field": "message",
+                "_time_field": "_time",
+            }
+        )
+        lines = [
+            json.dumps({"log_group": log_group, **entry}, ensure_ascii=False) for entry in entries
+        ]
+        req = urllib.request.Request(
+            f"{self.url}?{params}",
+            data="\n".join(lines).encode("utf-8"),
+            headers={"Content-Type": "application/stream+json"},
+            method="POST",
+        )
+        with urllib.request.urlopen(req, timeout=self.timeout) as res:
+            res.read()
+
+
+class LokiSink(LogSink):
+    """Loki の push API (/loki/api/v1/push) へ送信"""
+
+    def __init__(self, url: str, timeout: float = 2.0):
+        self.url = url
+        self.timeout = timeout
+
+    def send(self, log_group: str, entries: List[Dict[str, Any]]) -> None:
+        values = [
+            [str(_timestamp_ms(entry) * 1_000_000), json.dumps(entry, ensure_ascii=False)]
+            for entry in entries
+        ]
+        body = {"streams": [{"stream": {"log_group": log_group}, "values": values}]}
+        req = urllib.request.Request(
+            self.url,
+            data=json.dumps(body).encode("utf-8"),
+            headers={"Content-Type": "application/json"},
+            method="POST",
+        )
+        with urllib.request.urlopen(req, timeout=self.timeout) as res:
+            res.read()
+
+
+class CloudWatchLogsSink(LogSink):
+    """CloudWatch Logs (PutLogEvents) へ送信。ロググループ/ストリームは必要に応じて作成"""
+
+    def __init__(self, endpoint_url: Optional[str] = None, log_stream: Optional[str] = None):
+        self.endpoint_url = endpoint_url
+        self.log_stream = log_stream or socket.gethostname()
+        self._client = None
+        self._created: set = set()
+
+    def _get_client(self):
+        if self._client is None:
+            import boto3
+
+            self._client = boto3.client("logs", endpoint_url=self.endpoint_url)
+        return self._client
+
+    def _ensure_stream(self, client, log_group: str) -> None:
+        if log_group in self._created:
+            return
+        for create, kwargs in (
+            (client.create_log_group, {"logGroupName": log_group}),
+            (
+                client.create_log_stream,
+                {"logGroupName": log_group, "logStreamName": self.log_stream},
+            ),
+        ):
+            try:
+                create(**kwargs)
+            except client.exceptions.ResourceAlreadyExistsException:
+                pass
+        self._created.add(log_group)
+
+    def send(self, log_group: str, entries: List[Dict[str, Any]]) -> None:
+        client = self._get_client()
+        self._ensure_stream(client, log_group)
+        events = [
+            {"timestamp": _timestamp_ms(entry), "message": json.dumps(entry, ensure_ascii=False)}
+            for entry in entries
+        ]
+        events.sort(key=lambda e: e["timestamp"])
+        client.put_log_events(
+            logGroupName=log_group, logStreamName=self.log_stream, logEvents=events
+        )
+
+
+class FileSink(LogSink):
+    """ローカルファイルへ JSON Lines で追記 (ロググループごとに 1 ファイル)"""
+
+    def __init__(self, directory: str):
+        self.directory = directory
+
+    def path_for(self, log_group: str) -> str:
+        filename = re.sub(r"[^A-Za-z0-9._-]+", "_", log_group).strip("_") or "default"
+        return os.path.join(self.directory, f"{filename}.log")
+
+    def send(self, log_group: str, entries: List[Dict[str, Any]]) -> None:
+        os.makedirs(self.directory, exist_ok=True)
+        with open(self.path_for(log_group), "a", encoding="utf-8") as f:
+            for entry in entries:
+                f.write(json.dumps(entry, ensure_ascii=False) + "\n")
+
+
+def create_log_sink(sink_type: str, target: Optional[str] = None) -> LogSink:
+    """
+    種別からシンクを生成
+
+    Args:
+        sink_type: victorialogs / loki / cloudwatch / file
+        target: 送信先 URL (file の場合はディレクトリ)
+    """
+    sink_type = sink_type.lower()
+    if sink_type == "victorialogs":
+        return VictoriaLogsSink(target or os.environ["VICTORIALOGS_URL"])
+    if sink_type == "loki":
+        if not target:
+            raise ValueError("LOG_SINK_URL is required for loki sink")
+        return LokiSink(target)
+    if sink_type == "cloudwatch":
+        return CloudWatchLogsSink(endpoint_url=target)
+    if sink_type == "file":
+        return FileSink(target or "/var/log/esb")
+    raise ValueError(f"Unknown log sink: {sink_type}")
+
+
+def create_log_sink_from_env() -> Optional[LogSink]:
+    """LOG_SINK / LOG_SINK_URL からシンクを生成 (未設定なら None)"""
+    sink_type = os.environ.get("LOG_SINK")
+    if not sink_type:
+        return None
+    return create_log_sink(sink_type, os.environ.get("LOG_SINK_URL"))
+
+
+class BatchingLogHandler(logging.Handler):
+    """
+    ログをバッファに溜め、バックグラウンドスレッドからまとめて送信するハンドラー。
+
+    送信に失敗した場合はバッファに保持したまま指数バックオフで再送します。
+    バッファが max_buffer を超えた場合は古いものから破棄し、件数を dropped に記録します。
+    """
+
+    def __init__(
+        self,
+        sink: LogSink,
+        log_group: str,
+        batch_size: int = 100,
+        flush_interval: float = 1.0,
+        max_buffer: int = 10000,
+        max_backoff: float = 30.0,
+    ):
+        super().__init__()
+        self.sink = sink
+        self.log_group = log_group
+        self.batch_size = batch_size
+        self.flush_interval = flush_interval
+        self.max_backoff = max_backoff
+        self.dropped = 0
+
+        self._buffer: Deque[Dict[str, Any]] = deque(maxlen=max_buffer)
+        self._buffer_lock = threading.Lock()
+        self._send_lock = threading.Lock()
+        self._backoff = 0.0
+        self._retry_at = 0.0
+        self._wakeup = threading.Event()
+        self._closed = threading.Event()
+        self._thread = threading.Thread(target=self._run, name="log-exporter", daemon=True)
+        self._thread.start()
+
+    def emit(self, record: logging.LogRecord):
+        try:
+            msg = self.format(record)
+            try:
+                entry = json.loads(msg)
+            except json.JSONDecodeError:
+                entry = {"message": msg, "level": record.levelname}
+            if not isinstance(entry, dict):
+                entry = {"message": msg, "level": record.levelname}
+
+            with self._buffer_lock:
+                if len(self._buffer) == self._buffer.maxlen:
+                    self.dropped += 1
+                self._buffer.append(entry)
+                full = len(self._buffer) >= self.batch_size
+            if full:
+                self._wakeup.set()
+        except Exception:
+            self.handleError(record)
+
+    @property
+    def pending(self) -> int:
+        with self._buffer_lock:
+            return len(self._buffer)
+
+    def _send_pending(self) -> bool:
+        """バッファ内のログをバッチ単位で送信。すべて送信できた場合は True"""
+        with self._send_lock:
+            if time.monotonic() < self._retry_at:
+                return False
+            while True:
+                with self._buffer_lock:
+                    batch = list(itertools.islice(self._buffer, self.batch_size))
+                if not batch:
+                    return True
+                try:
+                    self.sink.send(self.log_group, batch)
+                except Exception as e:
+                    self._backoff = min(max(self._backoff * 2, 1.0), self.max_backoff)
+                    self._retry_at = time.monotonic() + self._backoff
+                    self._report_failure(e)
+                    return False
+
+                self._backoff = 0.0
+                with self._buffer_lock:
+                    # 送信中に溢れて破棄された分は除外済みのため、先頭から一致する分だけ除去
+                    for entry in batch:
+                        if self._buffer and self._buffer[0] is entry:
+                            self._buffer.popleft()
+
+    def _report_failure(self, error: Exception) -> None:
+        # sys.__stderr__ を使用して StreamToLogger による無限ループを回避
+        stream = getattr(sys, "__stderr__", sys.stderr)
+        try:
+            stream.write(
+                json.dumps(
+                    {
+                        "fallback": "log_export_failed",
+                        "error": str(error),
+                        "pending": self.pending,
+                        "dropped": self.dropped,
+                        "retry_in": self._backoff,
+                    }
+                )
+                + "\n"
+            )
+        except Exception:
+            pass
+
+    def _run(self):
+        while not self._closed.is_set():
+            self._wakeup.wait(self.flush_interval)
+            self._wakeup.clear()
+            self._send_pending()
+
+    def flush(self):
+        """バッファを同期的に送信 (バックオフ中は何もしない)"""
+        self._send_pending()
+
+    def close(self):
+        self._closed.set()
+        self._wakeup.set()
+        self._thread.join(timeout=self.flush_interval + 1)
+        self._send_pending()
+        super().close()
@@ -6,6 +6,7 @@ Provides:
 - CustomJsonFormatter: VictoriaLogs optimized JSON formatter
 - VictoriaLogsHandler: Direct HTTP logging with stdout fallback
 - configure_queue_logging: Async logging for long-lived processes
+  (or batched shipping via log_exporter when LOG_SINK is set)
 """
 
 import atexit
@@ -217,7 +218,19 @@ def configure_queue_logging(service_name: str, vl_url: str = None):
     """
     非同期QueueLoggingを構成する。
     Gateway/Managerなどの常駐プロセスで使用。
+
+    LOG_SINK が設定されている場合は BatchingLogHandler で指定のシンクへ送信する。
     """
+    from .log_exporter import BatchingLogHandler, create_log_sink_from_env
+
+    sink = create_log_sink_from_env()
+    if sink:
+        handler = BatchingLogHandler(sink, log_group=service_name)
+        handler.setFormatter(CustomJsonFormatter())
+        atexit.register(handler.close)
+        logging.getLogger().addHandler(handler)
+        return
+
     if not vl_url:
         return
 
@@ -24,6 +24,7 @@ class WorkerInfo:
     port: int = 8080  # サービスポート
     created_at: float = 0.0  # 作成時刻
     last_used_at: float = 0.0  # 最終使用時刻 (Auto-Scaling用)
+    adopted: bool = False  # 採用ラベルにより取り込んだ外部コンテナか
 
     def __eq__(self, other):
         if isinstance(other, WorkerInfo):
@@ -34,6 +35,23 @@ class WorkerInfo:
         return hash(self.id)
 
 
+class FunctionVolume(BaseModel):
+    """関数ごとの永続スクラッチボリューム設定"""
+
+    path: str = Field(..., description="コンテナ内のマウント先パス")
+    size_mb: Optional[int] = Field(None, ge=1, description="容量上限(MB、ソフトクォータ)")
+
+
+class ContainerSecurity(BaseModel):
+    """コンテナの実行ユーザーと capability 設定"""
+
+    user: Optional[str] = Field(None, description="実行ユーザー (例: 1000, sbx_user:sbx_group)")
+    groups: List[str] = Field(default_factory=list, description="補助グループ")
+    capabilities: Optional[List[str]] = Field(
+        None, description="付与する capability (未指定時は Orchestrator のデフォルト)"
+    )
+
+
 class ContainerProvisionRequest(BaseModel):
     """Gateway -> Manager: コンテナプロビジョニングリクエスト"""
 
@@ -41,6 +59,12 @@ class ContainerProvisionRequest(BaseModel):
     count: int = Field(default=1, ge=1, le=10, description="作成するコンテナ数")
     image: Optional[str] = Field(None, description="使用するDockerイメージ")
     env: Dict[str, str] = Field(default_factory=dict, description="注入する環境変数")
+    platform: Optional[str] = Field(None, description="イメージのプラットフォーム (例: linux/arm64)")
+    env_overrides: Dict[str, str] = Field(
+        default_factory=dict, description="この起動要求に限り env を上書きする環境変数"
+    )
+    volume: Optional[FunctionVolume] = Field(None, description="永続スクラッチボリューム")
+    security: Optional[ContainerSecurity] = Field(None, description="実行ユーザーと capability")
     request_id: Optional[str] = Field(None, description="トレース用リクエストID")
     dry_run: bool = Field(default=False, description="ドライラン")
 
@@ -71,6 +95,15 @@ class ContainerEnsureRequest(BaseModel):
     function_name: str = Field(..., description="起動対象の関数名（コンテナ名）")
     image: Optional[str] = Field(None, description="使用するDockerイメージ")
     env: Dict[str, str] = Field(default_factory=dict, description="注入する環境変数")
+    platform: Optional[str] = Field(None, description="イメージのプラットフォーム (例: linux/arm64)")
+    env_overrides: Dict[str, str] = Field(
+        default_factory=dict, description="この起動要求に限り env を上書きする環境変数"
+    )
+    volume: Optional[FunctionVolume] = Field(None, description="永続スクラッチボリューム")
+    security: Optional[ContainerSecurity] = Field(None, description="実行ユーザーと capability")
+    lease_ttl_seconds: Optional[int] = Field(
+        None, gt=0, description="指定時はコンテナにこの TTL のリースを発行する"
+    )
 
 
 class ContainerInfoResponse(BaseModel):
@@ -80,3 +113,65 @@ class ContainerInfoResponse(BaseModel):
 
     host: str = Field(..., description="コンテナのホスト名またはIP")
     port: int = Field(..., description="サービスポート番号")
+    lease_id: Optional[str] = Field(None, description="リース ID (リース要求時のみ)")
+    lease_expires_at: Optional[float] = Field(None, description="リースの有効期限 (エポック秒)")
+
+
+class LeaseRenewRequest(BaseModel):
+    """
+    Gateway -> Manager: リース更新
+    """
+
+    ttl_seconds: Optional[int] = Field(
+        None, gt=0, description="新しい TTL (未指定時は発行時の TTL)"
+    )
+
+
+class LeaseResponse(BaseModel):
+    """
+    Manager -> Gateway: リース情報
+    """
+
+    lease_id: str = Field(..., description="リース ID")
+    container_name: str = Field(..., description="対象コンテナ名")
+    function_name: str = Field(..., description="関数名")
+    ttl_seconds: int = Field(..., description="TTL (秒)")
+    expires_at: float = Field(..., description="有効期限 (エポック秒)")
+
+
+# =============================================================================
+# Image Management (Offline)
+# =============================================================================
+
+
+class ImageLoadResponse(BaseModel):
+    """
+    Manager -> Client: イメージアーカイブ読み込み結果
+    """
+
+    images: List[str] = Field(..., description="読み込まれたイメージ（タグまたはID）")
+
+
+# =============================================================================
+# Function Sync (Control Plane -> Agent)
+# =============================================================================
+
+
+class FunctionSpec(BaseModel):
+    """
+    同期対象の関数定義
+    """
+
+    name: str = Field(..., description="関数名")
+    image: Optional[str] = Field(None, description="イメージ (未指定時は {name}:latest)")
+    platform: Optional[str] = Field(None, description="プラットフォーム (例: linux/arm64)")
+
+
+class FunctionSyncRequest(BaseModel):
+    """
+    Control Plane -> Manager: あるべき関数の一覧
+    """
+
+    functions: List[FunctionSpec] = Field(..., description="あるべき関数の一覧")
+    prune: bool = Field(False, description="一覧にない関数のコンテナを削除する")
+    dry_run: bool = Field(False, description="差分の算出のみを行い適用しない")
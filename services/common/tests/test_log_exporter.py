@@ -0,0 +1,178 @@
+"""
+Log Exporter Unit Tests
+
+Tests for BatchingLogHandler and the pluggable log sinks.
+"""
+
+import json
+import logging
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+from services.common.core.log_exporter import (
+    BatchingLogHandler,
+    FileSink,
+    LogSink,
+    LokiSink,
+    create_log_sink,
+    log_group_name,
+)
+
+
+class RecordingSink(LogSink):
+    """送信内容を記録するテスト用シンク。fail=True の間は送信に失敗する"""
+
+    def __init__(self):
+        self.batches = []
+        self.fail = False
+
+    def send(self, log_group, entries):
+        if self.fail:
+            raise OSError("uplink down")
+        self.batches.append((log_group, list(entries)))
+
+
+def _record(msg: str) -> logging.LogRecord:
+    return logging.LogRecord(
+        name="test",
+        level=logging.INFO,
+        pathname=__file__,
+        lineno=1,
+        msg=msg,
+        args=(),
+        exc_info=None,
+    )
+
+
+@pytest.fixture
+def sink():
+    return RecordingSink()
+
+
+@pytest.fixture
+def handler(sink):
+    # バックグラウンド送信が干渉しないよう flush_interval を長くする
+    h = BatchingLogHandler(sink, log_group="/aws/lambda/echo", batch_size=2, flush_interval=60)
+    yield h
+    h.close()
+
+
+def test_flush_sends_in_batches(handler, sink):
+    for i in range(3):
+        handler.emit(_record(f"message {i}"))
+
+    handler.flush()
+
+    assert [len(entries) for _, entries in sink.batches] == [2, 1]
+    assert sink.batches[0][0] == "/aws/lambda/echo"
+    assert sink.batches[0][1][0]["message"] == "message 0"
+    assert handler.pending == 0
+
+
+def test_failed_send_keeps_buffer_and_backs_off(handler, sink):
+    """送信失敗時はバッファを保持し、バックオフ後に再送する"""
+    sink.fail = True
+    handler.emit(_record("kept"))
+
+    handler.flush()
+    assert handler.pending == 1
+
+    # バックオフ中は送信を試みない
+    sink.fail = False
+    handler.flush()
+    assert sink.batches == []
+
+    handler._retry_at = 0
+    handler.flush()
+    assert sink.batches[0][1][0]["message"] == "kept"
+    assert handler.pending == 0
+
+
+def test_buffer_overflow_drops_oldest(sink):
+    handler = BatchingLogHandler(sink, log_group="g", max_buffer=2, flush_interval=60)
+    try:
+        sink.fail = True
+        for i in range(3):
+            handler.emit(_record(f"m{i}"))
+
+        assert handler.pending == 2
+        assert handler.dropped == 1
+
+        sink.fail = False
+        handler._retry_at = 0
+        handler.flush()
+        assert [e["message"] for e in sink.batches[0][1]] == ["m1", "m2"]
+    finally:
+        handler.close()
+
+
+def test_file_sink_writes_per_log_group(tmp_path):
+    sink = FileSink(str(tmp_path))
+    sink.send("/aws/lambda/echo", [{"message": "a"}, {"message": "b"}])
+
+    path = tmp_path / "aws_lambda_echo.log"
+    lines = path.read_text().splitlines()
+    assert [json.loads(line)["message"] for line in lines] == ["a", "b"]
+
+
+def test_loki_sink_payload():
+    sink = LokiSink("http://loki:3100/loki/api/v1/push")
+    with patch("urllib.request.urlopen") as mock_urlopen:
+        mock_urlopen.return_value.__enter__.return_value = MagicMock()
+        sink.send("/aws/lambda/echo", [{"_time": "2024-01-01T00:00:00+00:00", "message": "hi"}])
+
+    req = mock_urlopen.call_args[0][0]
+    body = json.loads(req.data.decode("utf-8"))
+    stream = body["streams"][0]
+    assert stream["stream"] == {"log_group": "/aws/lambda/echo"}
+    assert stream["values"][0][0] == "1704067200000000000"
+    assert json.loads(stream["values"][0][1])["message"] == "hi"
+
+
+def test_log_group_name_pattern(monkeypatch):
+    assert log_group_name("echo") == "/aws/lambda/echo"
+    monkeypatch.setenv("LOG_GROUP_PATTERN", "edge/{function_name}")
+    assert log_group_name("echo") == "edge/echo"
+
+
+def test_create_log_sink_unknown_type():
+    with pytest.raises(ValueError):
+        create_log_sink("syslog")
+
+
+@pytest.mark.parametrize(
+    "env_name, context_name, expected",
+    [
+        ("echo-func", "ctx-func", "/aws/lambda/echo-func"),
+        (None, "ctx-func", "/aws/lambda/ctx-func"),
+    ],
+)
+def test_robust_lambda_logger_uses_function_log_group(
+    monkeypatch, env_name, context_name, expected
+):
+    """ロググループは service_name ではなく実行中の関数名から決まる"""
+    from services.common.core import lambda_logging
+
+    if env_name:
+        monkeypatch.setenv("AWS_LAMBDA_FUNCTION_NAME", env_name)
+    else:
+        monkeypatch.delenv("AWS_LAMBDA_FUNCTION_NAME", raising=False)
+    sink = RecordingSink()
+    monkeypatch.setattr(lambda_logging, "create_log_sink_from_env", lambda: sink)
+
+    @lambda_logging.robust_lambda_logger()
+    def handler(event, context):
+        return "ok"
+
+    root = logging.getLogger()
+    before = list(root.handlers)
+    try:
+        assert handler({}, MagicMock(function_name=context_name)) == "ok"
+        added = [h for h in root.handlers if h not in before]
+        assert [h.log_group for h in added] == [expected]
+    finally:
+        for h in root.handlers[:]:
+            if h not in before:
+                root.removeHandler(h)
+                h.close()
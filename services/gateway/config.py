@@ -6,6 +6,7 @@ pydantic-settings を使用して型安全性とデフォルト値を管理し
 """
 
 import sys
+from typing import Optional
 from pydantic import Field
 from services.common.core.config import BaseAppConfig
 
@@ -53,6 +54,31 @@ class GatewayConfig(BaseAppConfig):
     )
     LAMBDA_INVOKE_TIMEOUT: float = Field(default=30.0, description="Lambda呼び出しタイムアウト(秒)")
 
+    # デッドライン伝播・キャンセル設定
+    DISCONNECT_POLL_INTERVAL: float = Field(
+        default=0.5, description="呼び出し元の切断確認間隔(秒)"
+    )
+    RECYCLE_CANCELLED_WORKERS: bool = Field(
+        default=False, description="キャンセルされた呼び出しのワーカーを猶予期間後に破棄する"
+    )
+    CANCELLED_WORKER_GRACE_PERIOD: float = Field(
+        default=5.0, description="キャンセル後にワーカーを破棄するまでの猶予期間(秒)"
+    )
+
+    # ペイロード上限・スプール設定 (0 は無制限 / 無効)
+    MAX_REQUEST_PAYLOAD_BYTES: int = Field(
+        default=6 * 1024 * 1024, description="Invoke リクエストペイロードの上限(bytes)"
+    )
+    MAX_RESPONSE_PAYLOAD_BYTES: int = Field(
+        default=6 * 1024 * 1024, description="Invoke レスポンスペイロードの上限(bytes)"
+    )
+    RESPONSE_SPOOL_THRESHOLD_BYTES: int = Field(
+        default=0, description="これを超えるレスポンスを一時ファイルに退避する(bytes)"
+    )
+    RESPONSE_SPOOL_DIR: Optional[str] = Field(
+        default=None, description="レスポンス退避先ディレクトリ (未指定時はシステムの一時領域)"
+    )
+
     # サーキットブレーカー設定
     CIRCUIT_BREAKER_THRESHOLD: int = Field(default=5, description="失敗しきい値")
     CIRCUIT_BREAKER_RECOVERY_TIMEOUT: float = Field(
@@ -46,6 +46,35 @@ class LambdaExecutionError(LambdaInvokeError):
         super().__init__(f"Lambda execution failed for {function_name}: {cause}")
 
 
+class DeadlineExceededError(LambdaInvokeError):
+    """呼び出し元のデッドラインを過ぎた場合 (呼び出し前または RIE の応答待ち中) の例外"""
+
+    def __init__(self, function_name: str):
+        self.function_name = function_name
+        super().__init__(f"Deadline exceeded while invoking {function_name}")
+
+
+class InvocationCancelledError(LambdaInvokeError):
+    """呼び出し元の切断により Lambda 呼び出しを中断した場合の例外"""
+
+    def __init__(self, function_name: str):
+        self.function_name = function_name
+        super().__init__(f"Invocation of {function_name} cancelled by caller")
+
+
+class PayloadTooLargeError(LambdaInvokeError):
+    """リクエスト/レスポンスのペイロードが上限を超えた場合の例外"""
+
+    def __init__(self, function_name: str, direction: str, limit: int):
+        self.function_name = function_name
+        # "request" または "response"
+        self.direction = direction
+        self.limit = limit
+        super().__init__(
+            f"{direction.capitalize()} payload for {function_name} exceeds {limit} bytes"
+        )
+
+
 class OrchestratorError(LambdaInvokeError):
     """Orchestrator サービスからのエラー"""
 
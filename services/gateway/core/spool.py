@@ -0,0 +1,113 @@
+"""
+Response Spooling
+
+Lambda RIE のレスポンスをストリームで受信し、閾値を超えた分を一時ファイルに退避します。
+数百 MB のレスポンスでもメモリに全量を保持せず、呼び出し元へストリームで中継できます。
+"""
+
+import asyncio
+import logging
+import tempfile
+from typing import AsyncIterator, Optional
+
+import httpx
+
+from .exceptions import PayloadTooLargeError
+
+logger = logging.getLogger("gateway.spool")
+
+# 退避したボディを読み出す単位
+SPOOL_CHUNK_SIZE = 64 * 1024
+
+# 中継時に付け直すヘッダー (ボディはデコード済みのため)
+_STRIPPED_HEADERS = {"content-encoding", "content-length", "transfer-encoding"}
+
+
+class SpooledResponseStream(httpx.AsyncByteStream):
+    """一時ファイルに退避したレスポンスボディをチャンク単位で返すストリーム"""
+
+    def __init__(self, file, chunk_size: int = SPOOL_CHUNK_SIZE):
+        self.file = file
+        self.chunk_size = chunk_size
+
+    async def __aiter__(self) -> AsyncIterator[bytes]:
+        try:
+            while True:
+                chunk = await asyncio.to_thread(self.file.read, self.chunk_size)
+                if not chunk:
+                    break
+                yield chunk
+        finally:
+            self.file.close()
+
+    async def aclose(self) -> None:
+        self.file.close()
+
+
+def is_spooled(response: httpx.Response) -> bool:
+    """ボディが一時ファイルに退避されている (未読込の) レスポンスか"""
+    return isinstance(response.stream, SpooledResponseStream)
+
+
+async def spool_response(
+    function_name: str,
+    response: httpx.Response,
+    threshold: int,
+    max_bytes: int = 0,
+    directory: Optional[str] = None,
+) -> httpx.Response:
+    """
+    ストリーム受信中のレスポンスを読み込み、読み込み済みのレスポンスに置き換える
+
+    threshold 以下のボディはメモリ上に保持した通常のレスポンスを、
+    超えたボディは一時ファイルから読み出すストリームを持つレスポンスを返します。
+    threshold が 0 の場合は退避せず、常にメモリ上に保持します。
+    元のレスポンスは常にクローズされます。
+
+    Raises:
+        PayloadTooLargeError: ボディが max_bytes を超えた場合 (0 は無制限)
+    """
+    content_length = response.headers.get("content-length")
+    if max_bytes and content_length and content_length.isdigit():
+        if int(content_length) > max_bytes:
+            await response.aclose()
+            raise PayloadTooLargeError(function_name, "response", max_bytes)
+
+    # max_size=0 の SpooledTemporaryFile はファイルに書き出さない
+    spool = tempfile.SpooledTemporaryFile(max_size=max(threshold, 0), dir=directory)
+    size = 0
+    try:
+        async for chunk in response.aiter_bytes():
+            size += len(chunk)
+            if max_bytes and size > max_bytes:
+                raise PayloadTooLargeError(function_name, "response", max_bytes)
+            # 閾値を超えるとディスクへ書き出すため、イベントループを塞がないようスレッドで書き込む
+            await asyncio.to_thread(spool.write, chunk)
+    except BaseException:
+        spool.close()
+        raise
+    finally:
+        await response.aclose()
+
+    spool.seek(0)
+    headers = [
+        (key, value)
+        for key, value in response.headers.multi_items()
+        if key.lower() not in _STRIPPED_HEADERS
+    ]
+    headers.append(("content-length", str(size)))
+
+    if threshold <= 0 or size <= threshold:
+        content = spool.read()
+        spool.close()
+        return httpx.Response(
+            response.status_code, headers=headers, content=content, request=response.request
+        )
+
+    logger.info(f"Spooled {size} bytes response of {function_name} to disk")
+    return httpx.Response(
+        response.status_code,
+        headers=headers,
+        stream=SpooledResponseStream(spool),
+        request=response.request,
+    )
@@ -4,12 +4,60 @@ Gateway Utility Module
 
 import json
 import logging
-from typing import Dict, Any
+from typing import Dict, Any, Optional
 
 import httpx
+from starlette.requests import Request
+
+from .exceptions import PayloadTooLargeError
 
 logger = logging.getLogger("gateway.utils")
 
+# 呼び出し元のデッドライン (Unix エポックミリ秒) を伝播するヘッダー
+DEADLINE_HEADER = "X-Deadline"
+
+
+def parse_deadline_header(value: Optional[str]) -> Optional[float]:
+    """
+    X-Deadline ヘッダー (エポックミリ秒) をエポック秒に変換
+
+    不正な値の場合は None を返します（デッドラインなしとして扱う）。
+    """
+    if not value:
+        return None
+    try:
+        return int(value) / 1000.0
+    except ValueError:
+        logger.warning(f"Invalid {DEADLINE_HEADER} header: {value}")
+        return None
+
+
+async def read_request_body(request: Request, function_name: str, limit: int) -> bytes:
+    """
+    リクエストボディを上限付きで読み込む
+
+    Content-Length で事前に判定し、ヘッダーがない場合も読み込み中に上限を超えた時点で
+    打ち切ります (0 は無制限)。
+
+    Raises:
+        PayloadTooLargeError: ボディが上限を超えた場合
+    """
+    if not limit:
+        return await request.body()
+
+    content_length = request.headers.get("content-length")
+    if content_length and content_length.isdigit() and int(content_length) > limit:
+        raise PayloadTooLargeError(function_name, "request", limit)
+
+    chunks = []
+    size = 0
+    async for chunk in request.stream():
+        size += len(chunk)
+        if size > limit:
+            raise PayloadTooLargeError(function_name, "request", limit)
+        chunks.append(chunk)
+    return b"".join(chunks)
+
 
 def parse_lambda_response(lambda_response: httpx.Response) -> Dict[str, Any]:
     """
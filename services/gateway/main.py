@@ -7,8 +7,9 @@ routing.ymlに基づいてリクエストをLambda RIEコンテナに転送し
 
 from contextlib import asynccontextmanager
 from fastapi import FastAPI, Request, HTTPException, Header, BackgroundTasks
-from fastapi.responses import JSONResponse, Response
+from fastapi.responses import JSONResponse, Response, StreamingResponse
 from fastapi.exceptions import RequestValidationError
+from starlette.background import BackgroundTask
 from starlette.exceptions import HTTPException as StarletteHTTPException
 from typing import Optional
 from datetime import datetime, timezone
@@ -17,10 +18,16 @@ import logging
 import json
 from .config import config
 from .core.security import create_access_token
-from .core.utils import parse_lambda_response
+from .core.spool import is_spooled
+from .core.utils import (
+    DEADLINE_HEADER,
+    parse_deadline_header,
+    parse_lambda_response,
+    read_request_body,
+)
 from .models import AuthRequest, AuthResponse, AuthenticationResult
 from .client import OrchestratorClient
-from .services.container_manager import HttpContainerManager
+from .services.container_manager import HttpContainerManager, gateway_env_overrides
 from .core.event_builder import V1ProxyEventBuilder
 
 # Services Imports
@@ -45,6 +52,9 @@ from .core.exceptions import (
     ContainerStartError,
     LambdaExecutionError,
     FunctionNotFoundError,
+    DeadlineExceededError,
+    InvocationCancelledError,
+    PayloadTooLargeError,
 )
 
 # Logger setup
@@ -99,6 +109,9 @@ async def lifespan(app: FastAPI):
                 func_config = function_registry.get_function_config(function_name)
                 image = func_config.get("image") if func_config else None
                 env = func_config.get("environment", {}) if func_config else {}
+                platform = func_config.get("platform") if func_config else None
+                volume = func_config.get("volume") if func_config else None
+                security = func_config.get("security") if func_config else None
 
                 response = await self.client.post(
                     f"{self.manager_url}/containers/provision",
@@ -107,6 +120,10 @@ async def lifespan(app: FastAPI):
                         "count": 1,
                         "image": image,
                         "env": env,
+                        "platform": platform,
+                        "volume": volume,
+                        "security": security,
+                        "env_overrides": gateway_env_overrides(config),
                     },
                     timeout=config.ORCHESTRATOR_TIMEOUT,
                 )
@@ -371,11 +388,37 @@ async def health_check():
     return {"status": "healthy", "timestamp": datetime.now(timezone.utc).isoformat()}
 
 
+@app.get("/pools")
+async def pool_stats(request: Request, user_id: UserIdDep):
+    """コンテナプールの使用状況 (関数ごとのワーカー数と使用中ワーカー)"""
+    pool_manager = request.app.state.pool_manager
+    if pool_manager is None:
+        return {"enabled": False, "pools": {}}
+    return {"enabled": True, "pools": pool_manager.get_all_stats()}
+
+
 # ===========================================
 # AWS Lambda Service Compatible Endpoint
 # ===========================================
 
 
+def _payload_too_large_response(e: PayloadTooLargeError) -> JSONResponse:
+    """リクエスト超過は 413、Lambda のレスポンス超過は 502 として返す"""
+    status_code = 413 if e.direction == "request" else 502
+    return JSONResponse(status_code=status_code, content={"message": str(e)})
+
+
+async def _invoke_in_background(invoker, function_name: str, body: bytes) -> None:
+    """非同期呼び出し (Event) を実行し、結果を破棄する"""
+    resp = None
+    try:
+        resp = await invoker.invoke_function(function_name, body)
+    finally:
+        # 一時ファイルに退避したボディを GC を待たずに削除する
+        if resp is not None:
+            await resp.aclose()
+
+
 @app.post("/2015-03-31/functions/{function_name}/invocations")
 async def invoke_lambda_api(
     function_name: str,
@@ -402,25 +445,46 @@ async def invoke_lambda_api(
         )
 
     invocation_type = request.headers.get("X-Amz-Invocation-Type", "RequestResponse")
-    body = await request.body()
 
     try:
+        body = await read_request_body(request, function_name, config.MAX_REQUEST_PAYLOAD_BYTES)
         if invocation_type == "Event":
             # 非同期呼び出し：バックグラウンドで実行、即座に202を返す
-            background_tasks.add_task(invoker.invoke_function, function_name, body)
+            background_tasks.add_task(_invoke_in_background, invoker, function_name, body)
             return Response(status_code=202, content=b"", media_type="application/json")
         else:
-            # 同期呼び出し：結果を待って返す
-            resp = await invoker.invoke_function(function_name, body)
+            # 同期呼び出し：結果を待って返す（デッドライン伝播・切断時キャンセル）
+            resp = await invoker.invoke_function(
+                function_name,
+                body,
+                deadline=parse_deadline_header(request.headers.get(DEADLINE_HEADER)),
+                is_disconnected=request.is_disconnected,
+            )
             # RIEのレスポンスをそのままクライアント(boto3)へ中継
+            if is_spooled(resp):
+                # 一時ファイルに退避したボディはストリームで返し、送信後に破棄する
+                return StreamingResponse(
+                    resp.aiter_raw(),
+                    status_code=resp.status_code,
+                    headers=dict(resp.headers),
+                    media_type="application/json",
+                    background=BackgroundTask(resp.aclose),
+                )
             return Response(
                 content=resp.content,
                 status_code=resp.status_code,
                 headers=dict(resp.headers),
                 media_type="application/json",
             )
+    except PayloadTooLargeError as e:
+        return _payload_too_large_response(e)
     except ContainerStartError as e:
         return JSONResponse(status_code=503, content={"message": str(e)})
+    except DeadlineExceededError as e:
+        return JSONResponse(status_code=504, content={"message": str(e)})
+    except InvocationCancelledError as e:
+        # 呼び出し元は切断済みのため、レスポンスは届かない (nginx 互換の 499)
+        return JSONResponse(status_code=499, content={"message": str(e)})
     except LambdaExecutionError as e:
         return JSONResponse(status_code=502, content={"message": str(e)})
 
@@ -442,7 +506,9 @@ async def gateway_handler(
     """
     # Build Event and Invoke Lambda
     try:
-        body = await request.body()
+        body = await read_request_body(
+            request, target.container_name, config.MAX_REQUEST_PAYLOAD_BYTES
+        )
         event = await event_builder.build(
             request=request,
             body=body,
@@ -453,9 +519,16 @@ async def gateway_handler(
 
         # Invoke Lambda via LambdaInvoker (handles container ensure & RIE req)
         payload = json.dumps(event).encode("utf-8")
-        lambda_response = await invoker.invoke_function(target.container_name, payload)
+        lambda_response = await invoker.invoke_function(
+            target.container_name,
+            payload,
+            deadline=parse_deadline_header(request.headers.get(DEADLINE_HEADER)),
+            is_disconnected=request.is_disconnected,
+        )
 
-        # レスポンス変換
+        # レスポンス変換 (API Gateway 形式への変換には全量が必要)
+        if is_spooled(lambda_response):
+            await lambda_response.aread()
         result = parse_lambda_response(lambda_response)
         if "raw_content" in result:
             return Response(
@@ -484,8 +557,15 @@ async def gateway_handler(
         # LambdaInvoker might have already logged, but we keep this for gateway context
         orchestrator_client.invalidate_cache(target.container_name)
         return JSONResponse(status_code=502, content={"message": "Bad Gateway"})
+    except PayloadTooLargeError as e:
+        return _payload_too_large_response(e)
     except ContainerStartError as e:
         return JSONResponse(status_code=503, content={"message": str(e)})
+    except DeadlineExceededError as e:
+        return JSONResponse(status_code=504, content={"message": str(e)})
+    except InvocationCancelledError as e:
+        # 呼び出し元は切断済みのため、レスポンスは届かない (nginx 互換の 499)
+        return JSONResponse(status_code=499, content={"message": str(e)})
     except LambdaExecutionError as e:
         return JSONResponse(status_code=502, content={"message": str(e)})
 
@@ -1,4 +1,4 @@
-from typing import Protocol, Dict, Optional
+from typing import Any, Protocol, Dict, Optional
 import httpx
 import logging
 from ..config import GatewayConfig
@@ -16,9 +16,28 @@ from .container_cache import ContainerHostCache
 logger = logging.getLogger("gateway.container_manager")
 
 
+def gateway_env_overrides(config: GatewayConfig, trace_id: Optional[str] = None) -> Dict[str, str]:
+    """
+    Gateway が起動要求ごとに解決する環境変数 (Orchestrator へ env_overrides として渡す)
+
+    関数設定の environment より優先されます。
+    """
+    overrides = {"GATEWAY_INTERNAL_URL": config.GATEWAY_INTERNAL_URL}
+    if trace_id:
+        overrides["_X_AMZN_TRACE_ID"] = trace_id
+    return overrides
+
+
 class ContainerManagerProtocol(Protocol):
     async def get_lambda_host(
-        self, function_name: str, image: Optional[str], env: Dict[str, str]
+        self,
+        function_name: str,
+        image: Optional[str],
+        env: Dict[str, str],
+        platform: Optional[str] = None,
+        volume: Optional[Dict[str, Any]] = None,
+        security: Optional[Dict[str, Any]] = None,
+        env_overrides: Optional[Dict[str, str]] = None,
     ) -> str: ...
 
 
@@ -36,7 +55,14 @@ class HttpContainerManager:
         self.cache = cache or ContainerHostCache()
 
     async def get_lambda_host(
-        self, function_name: str, image: Optional[str], env: Dict[str, str]
+        self,
+        function_name: str,
+        image: Optional[str],
+        env: Dict[str, str],
+        platform: Optional[str] = None,
+        volume: Optional[Dict[str, Any]] = None,
+        security: Optional[Dict[str, Any]] = None,
+        env_overrides: Optional[Dict[str, str]] = None,
     ) -> str:
         # キャッシュチェック
         cached_host = self.cache.get(function_name)
@@ -48,7 +74,13 @@ class HttpContainerManager:
 
         # モデルを作成
         request_model = ContainerEnsureRequest(
-            function_name=function_name, image=image, env=env or {}
+            function_name=function_name,
+            image=image,
+            env=env or {},
+            platform=platform,
+            env_overrides=env_overrides or {},
+            volume=volume,
+            security=security,
         )
 
         # Trace ID / Request ID ヘッダーを伝播
@@ -185,11 +185,14 @@ class ContainerPool:
 
     @property
     def stats(self) -> dict:
-        """プール統計情報"""
+        """プール統計情報 (workers はワーカー名ごとの使用中フラグ)"""
+        idle = set(self._idle_workers)
         return {
             "function_name": self.function_name,
             "total_workers": len(self._all_workers),
             "idle": len(self._idle_workers),
+            "busy": len(self._all_workers - idle),
             "provisioning": self._provisioning_count,
             "max_capacity": self.max_capacity,
+            "workers": {w.name: w not in idle for w in self._all_workers},
         }
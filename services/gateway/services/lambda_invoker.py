@@ -5,11 +5,13 @@ ManagerClientを通じてコンテナを起動し、Lambda RIEに対してInvoke
 boto3.client('lambda').invoke() 互換のエンドポイント用のビジネスロジック層です。
 """
 
+import asyncio
 import logging
 import json
 import base64
+import time
 import httpx
-from typing import Dict, Optional, TYPE_CHECKING
+from typing import Awaitable, Callable, Dict, Optional, Set, TYPE_CHECKING
 from services.common.core.request_context import get_trace_id
 from services.gateway.services.function_registry import FunctionRegistry
 
@@ -17,14 +19,22 @@ if TYPE_CHECKING:
     from .pool_manager import PoolManager
 
 
-from services.gateway.services.container_manager import ContainerManagerProtocol
+from services.gateway.services.container_manager import (
+    ContainerManagerProtocol,
+    gateway_env_overrides,
+)
 from services.gateway.config import GatewayConfig
 from services.gateway.core.circuit_breaker import CircuitBreaker, CircuitBreakerOpenError
 from services.gateway.core.exceptions import (
     FunctionNotFoundError,
     ContainerStartError,
+    DeadlineExceededError,
+    InvocationCancelledError,
     LambdaExecutionError,
+    PayloadTooLargeError,
 )
+from services.gateway.core.spool import is_spooled, spool_response
+from services.gateway.core.utils import DEADLINE_HEADER
 
 
 logger = logging.getLogger("gateway.lambda_invoker")
@@ -54,9 +64,16 @@ class LambdaInvoker:
         self.pool_manager = pool_manager
         # 関数名ごとのブレーカーを保持
         self.breakers: Dict[str, CircuitBreaker] = {}
+        # キャンセル後のワーカー破棄タスク (GC 対策で参照を保持)
+        self._recycle_tasks: Set[asyncio.Task] = set()
 
     async def invoke_function(
-        self, function_name: str, payload: bytes, timeout: int = 300
+        self,
+        function_name: str,
+        payload: bytes,
+        timeout: int = 300,
+        deadline: Optional[float] = None,
+        is_disconnected: Optional[Callable[[], Awaitable[bool]]] = None,
     ) -> httpx.Response:
         """
         Lambda関数を呼び出す
@@ -65,6 +82,9 @@ class LambdaInvoker:
             function_name: 呼び出す関数名
             payload: リクエストボディ
             timeout: リクエストタイムアウト
+            deadline: 呼び出し元のデッドライン (エポック秒)。X-Deadline として RIE に伝播
+            is_disconnected: 呼び出し元の切断を確認するコールバック。
+                切断を検知した場合は RIE へのリクエストをキャンセルする
 
         Returns:
             Lambda RIEからのレスポンス
@@ -72,19 +92,30 @@ class LambdaInvoker:
         Raises:
             ContainerStartError: コンテナ起動失敗
             LambdaExecutionError: Lambda実行失敗
+            DeadlineExceededError: デッドライン超過
+            InvocationCancelledError: 呼び出し元の切断によるキャンセル
+            PayloadTooLargeError: リクエスト/レスポンスのペイロードが上限を超過
         """
         # config check
         func_config = self.registry.get_function_config(function_name)
         if func_config is None:
             raise FunctionNotFoundError(function_name)
 
+        max_request = self.config.MAX_REQUEST_PAYLOAD_BYTES
+        if max_request and len(payload) > max_request:
+            raise PayloadTooLargeError(function_name, "request", max_request)
+
+        # デッドラインの決定 (指定がなければタイムアウトから算出)
+        now = time.time()
+        has_caller_deadline = deadline is not None
+        if deadline is None:
+            deadline = now + timeout
+        elif deadline - now <= 0:
+            raise DeadlineExceededError(function_name)
+
         # Prepare env
         env = func_config.get("environment", {}).copy()
 
-        # Resolve Gateway URL using injected config
-        gateway_internal_url = self.config.GATEWAY_INTERNAL_URL
-        env["GATEWAY_INTERNAL_URL"] = gateway_internal_url
-
         # Inject _HANDLER env var for sitecustomize.py wrapper
         # This enables auto trace ID hydration via sitecustomize.py
         env.setdefault("_HANDLER", "lambda_function.lambda_handler")
@@ -92,10 +123,13 @@ class LambdaInvoker:
         # Trace ID Propagation
         trace_id = get_trace_id()
         logger.debug(f"Trace ID in Invoker: {trace_id}")
-        if trace_id:
-            env["_X_AMZN_TRACE_ID"] = trace_id
 
-        logger.debug(f"Passing env to manager for {function_name}: {env}")
+        # Gateway URL / Trace ID は起動要求ごとの上書きとして渡す
+        env_overrides = gateway_env_overrides(self.config, trace_id)
+
+        logger.debug(
+            f"Passing env to manager for {function_name}: {env} (overrides: {env_overrides})"
+        )
 
         # === POOL MODE vs LEGACY MODE ===
         worker = None
@@ -113,6 +147,10 @@ class LambdaInvoker:
                     function_name=function_name,
                     image=func_config.get("image"),
                     env=env,
+                    platform=func_config.get("platform"),
+                    volume=func_config.get("volume"),
+                    security=func_config.get("security"),
+                    env_overrides=env_overrides,
                 )
             except Exception as e:
                 raise ContainerStartError(function_name, e) from e
@@ -131,12 +169,17 @@ class LambdaInvoker:
             )
 
         breaker = self.breakers[function_name]
+        # レスポンスの上限超過・デッドライン超過はバックエンドの障害ではないため、
+        # ブレーカーの外で送出する
+        oversized: Optional[PayloadTooLargeError] = None
+        deadline_exceeded = False
 
         try:
             # ブレーカー経由で実行
             async def do_post():
                 headers = {
                     "Content-Type": "application/json",
+                    DEADLINE_HEADER: str(int(deadline * 1000)),
                 }
                 if trace_id:
                     # header value should be the full string (Root=...)
@@ -150,12 +193,29 @@ class LambdaInvoker:
 
                 logger.debug(f"Sending request to RIE with headers: {headers}")
 
-                response = await self.client.post(
-                    rie_url,
-                    content=payload,
-                    headers=headers,
-                    timeout=timeout,
-                )
+                nonlocal oversized, deadline_exceeded
+                # コールドスタートやプール待ちで消費した分を除き、残り時間で打ち切る
+                post_timeout = timeout
+                if has_caller_deadline:
+                    remaining = deadline - time.time()
+                    if remaining <= 0:
+                        deadline_exceeded = True
+                        return None
+                    post_timeout = min(timeout, remaining)
+                try:
+                    response = await self._post(
+                        function_name, rie_url, payload, headers, post_timeout
+                    )
+                except PayloadTooLargeError as e:
+                    oversized = e
+                    return None
+                except httpx.TimeoutException:
+                    if post_timeout < timeout:
+                        # デッドラインで短縮したタイムアウトによる打ち切り
+                        deadline_exceeded = True
+                        return None
+                    raise
+                spooled = is_spooled(response)
 
                 # 判定: 回路を遮断すべき「失敗」かどうか
                 is_failure = False
@@ -165,7 +225,7 @@ class LambdaInvoker:
                     is_failure = True
                 elif response.status_code == 200:
                     try:
-                        if len(response.content) < 1024 * 10:
+                        if not spooled and len(response.content) < 1024 * 10:
                             data = response.json()
                             if isinstance(data, dict) and (
                                 "errorType" in data or "errorMessage" in data
@@ -175,20 +235,51 @@ class LambdaInvoker:
                         pass
 
                 if is_failure:
+                    if spooled:
+                        await response.aclose()
                     if response.status_code >= 400:
                         response.raise_for_status()
                     else:
                         raise httpx.HTTPStatusError(
-                            f"Lambda Logical Error: {response.text[:100]}",
+                            "Lambda Logical Error: "
+                            + ("<spooled>" if spooled else response.text[:100]),
                             request=response.request,
                             response=response,
                         )
 
                 return response
 
-            result = await breaker.call(do_post)
+            if is_disconnected is None:
+                result = await breaker.call(do_post)
+            else:
+                result = await self._run_unless_disconnected(
+                    function_name, breaker.call(do_post), is_disconnected
+                )
+            if oversized is not None:
+                raise oversized
+            if deadline_exceeded:
+                raise DeadlineExceededError(function_name)
             return result
 
+        except InvocationCancelledError:
+            logger.warning(f"Invocation of {function_name} cancelled: caller disconnected")
+            if (
+                worker is not None
+                and self.pool_manager is not None
+                and self.config.RECYCLE_CANCELLED_WORKERS
+            ):
+                # RIE は切断後もハンドラを実行し続けるため、プールには戻さず破棄する
+                task = asyncio.create_task(self._recycle_after_grace(function_name, worker))
+                self._recycle_tasks.add(task)
+                task.add_done_callback(self._recycle_tasks.discard)
+                worker = None  # prevent release in finally
+            raise
+        except PayloadTooLargeError:
+            logger.warning(f"Response payload of {function_name} exceeds the limit")
+            raise
+        except DeadlineExceededError:
+            logger.warning(f"Deadline exceeded while invoking {function_name}")
+            raise
         except CircuitBreakerOpenError as e:
             logger.error(f"Circuit breaker open for {function_name}: {e}")
             raise LambdaExecutionError(function_name, "Circuit Breaker Open") from e
@@ -213,5 +304,59 @@ class LambdaInvoker:
                 except Exception as e:
                     logger.error(f"Failed to release worker for {function_name}: {e}")
 
+    async def _post(
+        self,
+        function_name: str,
+        url: str,
+        payload: bytes,
+        headers: Dict[str, str],
+        timeout: float,
+    ) -> httpx.Response:
+        """
+        RIE へ POST し、レスポンスを返す
+
+        レスポンスは常にストリームで受信し、MAX_RESPONSE_PAYLOAD_BYTES を超えた時点で打ち切る。
+        RESPONSE_SPOOL_THRESHOLD_BYTES が設定されている場合は、閾値を超えたボディを一時ファイルに退避する。
+        """
+        async with self.client.stream(
+            "POST", url, content=payload, headers=headers, timeout=timeout
+        ) as response:
+            return await spool_response(
+                function_name,
+                response,
+                self.config.RESPONSE_SPOOL_THRESHOLD_BYTES,
+                max_bytes=self.config.MAX_RESPONSE_PAYLOAD_BYTES,
+                directory=self.config.RESPONSE_SPOOL_DIR,
+            )
+
+    async def _run_unless_disconnected(
+        self,
+        function_name: str,
+        invocation: Awaitable[httpx.Response],
+        is_disconnected: Callable[[], Awaitable[bool]],
+    ) -> httpx.Response:
+        """呼び出し元の切断を監視しながら実行し、切断時は RIE へのリクエストをキャンセル"""
+        task = asyncio.ensure_future(invocation)
+        while True:
+            done, _ = await asyncio.wait({task}, timeout=self.config.DISCONNECT_POLL_INTERVAL)
+            if task in done:
+                return task.result()
+            if await is_disconnected():
+                task.cancel()
+                try:
+                    await task
+                except asyncio.CancelledError:
+                    pass
+                raise InvocationCancelledError(function_name)
+
+    async def _recycle_after_grace(self, function_name: str, worker) -> None:
+        """猶予期間の経過後、キャンセルを無視して実行中のワーカーを破棄"""
+        await asyncio.sleep(self.config.CANCELLED_WORKER_GRACE_PERIOD)
+        try:
+            await self.pool_manager.recycle_worker(function_name, worker)
+            logger.info(f"Recycled worker {worker.name} after cancelled invocation")
+        except Exception as e:
+            logger.error(f"Failed to recycle worker {worker.name}: {e}")
+
 
 # Backward compatibility or helper if needed? No, we are fully refactoring to DI.
@@ -77,6 +77,11 @@ class PoolManager:
         if function_name in self._pools:
             await self._pools[function_name].evict(worker)
 
+    async def recycle_worker(self, function_name: str, worker: WorkerInfo) -> None:
+        """ワーカーをプールから除外し、コンテナを削除"""
+        await self.evict_worker(function_name, worker)
+        await self.provision_client.delete_container(worker.id)
+
     def get_all_worker_names(self) -> Dict[str, List[str]]:
         """Heartbeat用: 全プールの全Worker Nameを収集 (Busy + Idle)"""
         result = {}
@@ -84,6 +89,10 @@ class PoolManager:
             result[fname] = pool.get_all_names()
         return result
 
+    def get_all_stats(self) -> Dict[str, dict]:
+        """全プールの統計情報 (使用中ワーカー数を含む) を収集"""
+        return {fname: pool.stats for fname, pool in self._pools.items()}
+
     def _extract_function_name(self, name: str) -> Optional[str]:
         """
         コンテナ名から関数名を抽出
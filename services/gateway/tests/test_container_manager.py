@@ -31,7 +31,34 @@ async def test_get_lambda_host_params():
     mock_client.post.assert_called_once()
     call_args = mock_client.post.call_args
     assert call_args[0][0] == expected_url
-    assert call_args[1]["json"] == {"function_name": function_name, "image": image, "env": env}
+    assert call_args[1]["json"] == {
+        "function_name": function_name,
+        "image": image,
+        "env": env,
+        "platform": None,
+        "env_overrides": {},
+        "volume": None,
+        "security": None,
+        "lease_ttl_seconds": None,
+    }
+
+
+@pytest.mark.asyncio
+async def test_get_lambda_host_sends_env_overrides():
+    """起動要求ごとの上書きを env_overrides として送る"""
+    mock_client = AsyncMock()
+    mock_response = MagicMock()
+    mock_response.json.return_value = {"host": "1.2.3.4", "port": 8080}
+    mock_client.post.return_value = mock_response
+
+    manager = HttpContainerManager(client=mock_client, config=GatewayConfig())
+    await manager.get_lambda_host(
+        "test-func", "img", {"FOO": "BAR"}, env_overrides={"_X_AMZN_TRACE_ID": "Root=1"}
+    )
+
+    sent = mock_client.post.call_args[1]["json"]
+    assert sent["env"] == {"FOO": "BAR"}
+    assert sent["env_overrides"] == {"_X_AMZN_TRACE_ID": "Root=1"}
 
 
 @pytest.mark.asyncio
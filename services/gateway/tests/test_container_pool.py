@@ -259,3 +259,59 @@ class TestContainerPoolConcurrency:
 
         assert len(successes) == 3
         assert len(timeouts) == 2
+
+
+class TestContainerPoolBusyTracking:
+    """Tests for busy/idle worker stats"""
+
+    @pytest.fixture
+    def pool(self):
+        from services.gateway.services.container_pool import ContainerPool
+
+        return ContainerPool(function_name="test-function", max_capacity=2, acquire_timeout=1.0)
+
+    @pytest.mark.asyncio
+    async def test_acquire_and_release_update_busy(self, pool):
+        """acquire() で使用中になり、release() でアイドルに戻る"""
+        from services.common.models.internal import WorkerInfo
+
+        worker = WorkerInfo(id="c1", name="w1", ip_address="10.0.0.1")
+        acquired = await pool.acquire(AsyncMock(return_value=[worker]))
+
+        assert pool.stats["busy"] == 1
+        assert pool.stats["workers"] == {"w1": True}
+
+        await pool.release(acquired)
+
+        assert pool.stats["busy"] == 0
+        assert pool.stats["idle"] == 1
+        assert pool.stats["workers"] == {"w1": False}
+
+    @pytest.mark.asyncio
+    async def test_concurrent_request_provisions_second_worker(self, pool):
+        """使用中のワーカーがあれば、2件目の同時リクエストは別ワーカーを起動する"""
+        from services.common.models.internal import WorkerInfo
+
+        workers = [
+            WorkerInfo(id="c1", name="w1", ip_address="10.0.0.1"),
+            WorkerInfo(id="c2", name="w2", ip_address="10.0.0.2"),
+        ]
+        provision_callback = AsyncMock(side_effect=[[workers[0]], [workers[1]]])
+
+        first = await pool.acquire(provision_callback)
+        second = await pool.acquire(provision_callback)
+
+        assert first.id != second.id
+        assert pool.stats["busy"] == 2
+
+    @pytest.mark.asyncio
+    async def test_evict_removes_busy_worker(self, pool):
+        from services.common.models.internal import WorkerInfo
+
+        worker = WorkerInfo(id="c1", name="w1", ip_address="10.0.0.1")
+        acquired = await pool.acquire(AsyncMock(return_value=[worker]))
+
+        await pool.evict(acquired)
+
+        assert pool.stats["busy"] == 0
+        assert pool.stats["workers"] == {}
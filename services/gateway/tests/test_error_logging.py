@@ -121,7 +121,7 @@ async def test_lambda_connection_error_logged_at_error_level(caplog):
 
     with TestClient(app) as client:
         # Trigger Lambda connection error
-        mock_client.post.side_effect = httpx.ConnectError("Connection refused")
+        mock_client.stream.side_effect = httpx.ConnectError("Connection refused")
         client.get("/test-path", headers={"Authorization": "Bearer valid-token"})
 
     # Clean up overrides
@@ -177,7 +177,7 @@ async def test_lambda_connection_error_includes_detailed_info(caplog):
 
     with TestClient(app) as client:
         # Trigger Lambda connection error
-        mock_client.post.side_effect = httpx.ConnectTimeout("Timeout after 30s")
+        mock_client.stream.side_effect = httpx.ConnectTimeout("Timeout after 30s")
 
         client.get("/test-path", headers={"Authorization": "Bearer valid-token"})
 
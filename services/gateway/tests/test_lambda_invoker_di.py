@@ -1,3 +1,4 @@
+import httpx
 import pytest
 from unittest.mock import MagicMock, AsyncMock, patch
 from services.gateway.services.lambda_invoker import LambdaInvoker
@@ -5,6 +6,19 @@ from services.gateway.services.function_registry import FunctionRegistry
 from services.gateway.config import GatewayConfig
 
 
+class StreamContext:
+    """client.stream() の戻り値のモック (async with でレスポンスを返す)"""
+
+    def __init__(self, response):
+        self.response = response
+
+    async def __aenter__(self):
+        return self.response
+
+    async def __aexit__(self, *exc_info):
+        return False
+
+
 @pytest.mark.asyncio
 async def test_lambda_invoker_di_initialization():
     """Test LambdaInvoker can be initialized with dependencies"""
@@ -48,12 +62,12 @@ async def test_lambda_invoker_invoke_flow():
     container_manager.get_lambda_host.return_value = "10.0.0.5"
 
     # Mock HTTP Client - return valid JSON response (not an error)
-    mock_response = MagicMock()
-    mock_response.status_code = 200
-    mock_response.content = b'{"statusCode": 200, "body": "OK"}'
-    mock_response.json.return_value = {"statusCode": 200, "body": "OK"}
-    mock_response.headers = {}
-    client.post.return_value = mock_response
+    mock_response = httpx.Response(
+        200,
+        json={"statusCode": 200, "body": "OK"},
+        request=httpx.Request("POST", "http://10.0.0.5"),
+    )
+    client.stream = MagicMock(return_value=StreamContext(mock_response))
 
     # Act
     await invoker.invoke_function(function_name, payload)
@@ -71,19 +85,19 @@ async def test_lambda_invoker_invoke_flow():
     # Verify environment extension
     called_env = kwargs.get("env") or args[2]
     assert called_env["VAR"] == "VAL"
-    assert called_env["GATEWAY_INTERNAL_URL"] == "http://gateway-internal"
+    # Gateway が解決する値は起動要求ごとの上書きとして渡す
+    assert kwargs["env_overrides"]["GATEWAY_INTERNAL_URL"] == "http://gateway-internal"
 
     # 3. HTTP Client called
     expected_url = f"http://10.0.0.5:{config.LAMBDA_PORT}/2015-03-31/functions/function/invocations"
-    client.post.assert_called_once()
-    assert client.post.call_args[0][0] == expected_url
+    client.stream.assert_called_once()
+    assert client.stream.call_args[0] == ("POST", expected_url)
 
 
 @pytest.mark.asyncio
 async def test_lambda_invoker_logging_on_error():
     """Test LambdaInvoker logs errors with extra context"""
     from services.gateway.core.exceptions import LambdaExecutionError
-    import httpx
 
     client = AsyncMock()
     registry = MagicMock(spec=FunctionRegistry)
@@ -95,7 +109,7 @@ async def test_lambda_invoker_logging_on_error():
     # Setup mocks
     registry.get_function_config.return_value = {"image": "img", "environment": {}}
     container_manager.get_lambda_host.return_value = "host"
-    client.post.side_effect = httpx.RequestError("Connection failed")
+    client.stream = MagicMock(side_effect=httpx.RequestError("Connection failed"))
 
     with patch("services.gateway.services.lambda_invoker.logger") as mock_logger:
         with pytest.raises(LambdaExecutionError):
@@ -105,3 +119,24 @@ async def test_lambda_invoker_logging_on_error():
         call_args = mock_logger.error.call_args
         assert "function_name" in call_args.kwargs["extra"]
         assert call_args.kwargs["extra"]["function_name"] == "error-func"
+
+
+@pytest.mark.asyncio
+async def test_lambda_invoker_rejects_request_over_limit():
+    """MAX_REQUEST_PAYLOAD_BYTES を超えるペイロードはコンテナを起動せずに拒否する"""
+    from services.gateway.core.exceptions import PayloadTooLargeError
+
+    client = AsyncMock()
+    registry = MagicMock(spec=FunctionRegistry)
+    container_manager = AsyncMock()
+    config = GatewayConfig(MAX_REQUEST_PAYLOAD_BYTES=16)
+
+    invoker = LambdaInvoker(client, registry, container_manager, config)
+    registry.get_function_config.return_value = {"image": "img", "environment": {}}
+
+    with pytest.raises(PayloadTooLargeError) as exc_info:
+        await invoker.invoke_function("big-func", b"x" * 17)
+
+    assert exc_info.value.direction == "request"
+    container_manager.get_lambda_host.assert_not_called()
+    client.stream.assert_not_called()
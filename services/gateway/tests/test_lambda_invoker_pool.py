@@ -4,11 +4,29 @@ Tests for LambdaInvoker Pool Mode (Auto-Scaling)
 TDD: Tests for pool-based invocation with self-healing.
 """
 
+import asyncio
+
 import pytest
 from unittest.mock import AsyncMock, MagicMock, patch
 import httpx
 
 
+class StreamContext:
+    """client.stream() の戻り値のモック (async with でレスポンスを返す)"""
+
+    def __init__(self, response, delay: float = 0):
+        self.response = response
+        self.delay = delay
+
+    async def __aenter__(self):
+        if self.delay:
+            await asyncio.sleep(self.delay)
+        return self.response
+
+    async def __aexit__(self, *exc_info):
+        return False
+
+
 class TestLambdaInvokerPoolMode:
     """Tests for LambdaInvoker with PoolManager integration"""
 
@@ -33,6 +51,10 @@ class TestLambdaInvokerPoolMode:
         config.LAMBDA_PORT = 8080
         config.CIRCUIT_BREAKER_THRESHOLD = 5
         config.CIRCUIT_BREAKER_RECOVERY_TIMEOUT = 30
+        config.MAX_REQUEST_PAYLOAD_BYTES = 6 * 1024 * 1024
+        config.MAX_RESPONSE_PAYLOAD_BYTES = 6 * 1024 * 1024
+        config.RESPONSE_SPOOL_THRESHOLD_BYTES = 0
+        config.RESPONSE_SPOOL_DIR = None
         return config
 
     @pytest.fixture
@@ -52,12 +74,11 @@ class TestLambdaInvokerPoolMode:
     def mock_http_client(self):
         """Mock httpx.AsyncClient"""
         client = MagicMock(spec=httpx.AsyncClient)
-        response = MagicMock(spec=httpx.Response)
-        response.status_code = 200
-        response.headers = {}
-        response.content = b'{"result": "ok"}'
-        response.json = MagicMock(return_value={"result": "ok"})
-        client.post = AsyncMock(return_value=response)
+        client.stream = MagicMock(
+            side_effect=lambda method, url, **kwargs: StreamContext(
+                httpx.Response(200, json={"result": "ok"}, request=httpx.Request(method, url))
+            )
+        )
         return client
 
     @pytest.fixture
@@ -136,7 +157,7 @@ class TestLambdaInvokerPoolMode:
 
         worker = WorkerInfo(id="c_dead", name="dead-worker", ip_address="10.0.0.99")
         mock_pool_manager.acquire_worker = AsyncMock(return_value=worker)
-        mock_http_client.post = AsyncMock(side_effect=httpx.ConnectError("Connection refused"))
+        mock_http_client.stream = MagicMock(side_effect=httpx.ConnectError("Connection refused"))
 
         invoker = LambdaInvoker(
             client=mock_http_client,
@@ -175,3 +196,165 @@ class TestLambdaInvokerPoolMode:
         await invoker.invoke_function("hello-world", b'{}')
 
         mock_container_manager.get_lambda_host.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_invoke_propagates_deadline_header(
+        self,
+        mock_http_client,
+        mock_registry,
+        mock_container_manager,
+        mock_config,
+        mock_pool_manager,
+    ):
+        """デッドラインは X-Deadline (エポックミリ秒) として RIE に伝播し、タイムアウトも短縮する"""
+        import time
+        from services.gateway.services.lambda_invoker import LambdaInvoker
+
+        invoker = LambdaInvoker(
+            client=mock_http_client,
+            registry=mock_registry,
+            container_manager=mock_container_manager,
+            config=mock_config,
+            pool_manager=mock_pool_manager,
+        )
+
+        deadline = time.time() + 10
+        await invoker.invoke_function("hello-world", b"{}", deadline=deadline)
+
+        call_kwargs = mock_http_client.stream.call_args.kwargs
+        assert call_kwargs["headers"]["X-Deadline"] == str(int(deadline * 1000))
+        assert call_kwargs["timeout"] <= 10
+
+    @pytest.mark.asyncio
+    async def test_invoke_rejects_expired_deadline(
+        self,
+        mock_http_client,
+        mock_registry,
+        mock_container_manager,
+        mock_config,
+        mock_pool_manager,
+    ):
+        """デッドライン超過時はワーカーを取得せずに失敗する"""
+        import time
+        from services.gateway.services.lambda_invoker import LambdaInvoker
+        from services.gateway.core.exceptions import DeadlineExceededError
+
+        invoker = LambdaInvoker(
+            client=mock_http_client,
+            registry=mock_registry,
+            container_manager=mock_container_manager,
+            config=mock_config,
+            pool_manager=mock_pool_manager,
+        )
+
+        with pytest.raises(DeadlineExceededError):
+            await invoker.invoke_function("hello-world", b"{}", deadline=time.time() - 1)
+
+        mock_pool_manager.acquire_worker.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_invoke_deadline_consumed_by_worker_acquisition(
+        self,
+        mock_http_client,
+        mock_registry,
+        mock_container_manager,
+        mock_config,
+        mock_pool_manager,
+    ):
+        """ワーカー取得中にデッドラインを過ぎた場合は RIE に送信しない"""
+        import asyncio
+        import time
+        from services.gateway.services.lambda_invoker import LambdaInvoker
+        from services.gateway.core.exceptions import DeadlineExceededError
+
+        worker = await mock_pool_manager.acquire_worker("hello-world")
+
+        async def slow_acquire(function_name):
+            await asyncio.sleep(0.1)
+            return worker
+
+        mock_pool_manager.acquire_worker = AsyncMock(side_effect=slow_acquire)
+        invoker = LambdaInvoker(
+            client=mock_http_client,
+            registry=mock_registry,
+            container_manager=mock_container_manager,
+            config=mock_config,
+            pool_manager=mock_pool_manager,
+        )
+
+        with pytest.raises(DeadlineExceededError):
+            await invoker.invoke_function("hello-world", b"{}", deadline=time.time() + 0.05)
+
+        mock_http_client.stream.assert_not_called()
+        assert invoker.breakers["hello-world"].failures == 0
+
+    @pytest.mark.asyncio
+    async def test_invoke_timeout_at_deadline_is_not_breaker_failure(
+        self,
+        mock_http_client,
+        mock_registry,
+        mock_container_manager,
+        mock_config,
+        mock_pool_manager,
+    ):
+        """デッドラインで短縮したタイムアウトは 504 扱いとし、ブレーカーの失敗に数えない"""
+        import time
+        from services.gateway.services.lambda_invoker import LambdaInvoker
+        from services.gateway.core.exceptions import DeadlineExceededError
+
+        mock_http_client.stream = MagicMock(side_effect=httpx.ReadTimeout("timed out"))
+        invoker = LambdaInvoker(
+            client=mock_http_client,
+            registry=mock_registry,
+            container_manager=mock_container_manager,
+            config=mock_config,
+            pool_manager=mock_pool_manager,
+        )
+
+        with pytest.raises(DeadlineExceededError):
+            await invoker.invoke_function("hello-world", b"{}", deadline=time.time() + 10)
+
+        assert invoker.breakers["hello-world"].failures == 0
+        mock_pool_manager.release_worker.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_invoke_cancelled_on_disconnect_recycles_worker(
+        self,
+        mock_http_client,
+        mock_registry,
+        mock_container_manager,
+        mock_config,
+        mock_pool_manager,
+    ):
+        """呼び出し元が切断した場合はリクエストをキャンセルし、猶予期間後にワーカーを破棄する"""
+        import asyncio
+        from services.gateway.services.lambda_invoker import LambdaInvoker
+        from services.gateway.core.exceptions import InvocationCancelledError
+        from services.common.models.internal import WorkerInfo
+
+        worker = WorkerInfo(id="c1", name="w1", ip_address="10.0.0.1")
+        mock_pool_manager.acquire_worker = AsyncMock(return_value=worker)
+        mock_pool_manager.recycle_worker = AsyncMock()
+        mock_config.DISCONNECT_POLL_INTERVAL = 0.01
+        mock_config.RECYCLE_CANCELLED_WORKERS = True
+        mock_config.CANCELLED_WORKER_GRACE_PERIOD = 0
+
+        mock_http_client.stream = MagicMock(return_value=StreamContext(None, delay=10))
+
+        invoker = LambdaInvoker(
+            client=mock_http_client,
+            registry=mock_registry,
+            container_manager=mock_container_manager,
+            config=mock_config,
+            pool_manager=mock_pool_manager,
+        )
+
+        with pytest.raises(InvocationCancelledError):
+            await invoker.invoke_function(
+                "hello-world", b"{}", is_disconnected=AsyncMock(return_value=True)
+            )
+
+        await asyncio.gather(*invoker._recycle_tasks)
+
+        mock_pool_manager.release_worker.assert_not_called()
+        mock_pool_manager.recycle_worker.assert_awaited_once_with("hello-world", worker)
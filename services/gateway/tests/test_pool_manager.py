@@ -201,3 +201,40 @@ class TestPoolManagerHeartbeat:
         assert len(names["function-b"]) == 1
         assert "w1" in names["function-a"]
         assert "w2" in names["function-b"]
+
+
+class TestPoolStatsEndpoint:
+    """GET /pools でプールの使用状況を公開する"""
+
+    @pytest.fixture
+    def app(self):
+        from services.gateway.main import app
+        from services.gateway.api.deps import verify_authorization
+
+        app.dependency_overrides[verify_authorization] = lambda: "test-user"
+        yield app
+        app.dependency_overrides = {}
+
+    def test_returns_pool_stats(self, app):
+        from fastapi.testclient import TestClient
+
+        stats = {"test-function": {"total_workers": 1, "busy": 1, "workers": {"w1": True}}}
+        pool_manager = MagicMock()
+        pool_manager.get_all_stats.return_value = stats
+
+        with TestClient(app) as client:
+            app.state.pool_manager = pool_manager
+            response = client.get("/pools")
+
+        assert response.status_code == 200
+        assert response.json() == {"enabled": True, "pools": stats}
+
+    def test_reports_disabled_when_pooling_off(self, app):
+        from fastapi.testclient import TestClient
+
+        with TestClient(app) as client:
+            app.state.pool_manager = None
+            response = client.get("/pools")
+
+        assert response.status_code == 200
+        assert response.json() == {"enabled": False, "pools": {}}
@@ -0,0 +1,150 @@
+import pytest
+import httpx
+import respx
+from unittest.mock import MagicMock, AsyncMock
+from services.gateway.config import GatewayConfig
+from services.gateway.core.exceptions import PayloadTooLargeError
+from services.gateway.core.spool import SpooledResponseStream, is_spooled, spool_response
+from services.gateway.services.function_registry import FunctionRegistry
+from services.gateway.services.lambda_invoker import LambdaInvoker
+
+RIE_URL = "http://10.0.0.1:8080/2015-03-31/functions/function/invocations"
+
+
+async def _stream(client: httpx.AsyncClient) -> httpx.Response:
+    request = client.build_request("POST", RIE_URL, content=b"{}")
+    return await client.send(request, stream=True)
+
+
+@pytest.mark.asyncio
+async def test_event_invocation_closes_response():
+    """非同期呼び出し (Event) のバックグラウンド処理はレスポンスを必ずクローズする"""
+    from services.gateway.main import _invoke_in_background
+
+    response = MagicMock()
+    response.aclose = AsyncMock()
+    invoker = MagicMock()
+    invoker.invoke_function = AsyncMock(return_value=response)
+
+    await _invoke_in_background(invoker, "big-func", b"{}")
+
+    invoker.invoke_function.assert_awaited_once_with("big-func", b"{}")
+    response.aclose.assert_awaited_once()
+
+
+@pytest.mark.asyncio
+@respx.mock
+async def test_spool_response_keeps_small_body_in_memory():
+    """閾値以下のボディは通常の (読み込み済み) レスポンスとして返す"""
+    respx.post(RIE_URL).mock(return_value=httpx.Response(200, content=b'{"ok": true}'))
+
+    async with httpx.AsyncClient() as client:
+        result = await spool_response("func", await _stream(client), threshold=1024)
+
+    assert not is_spooled(result)
+    assert result.json() == {"ok": True}
+    assert result.headers["content-length"] == str(len(b'{"ok": true}'))
+
+
+@pytest.mark.asyncio
+@respx.mock
+async def test_spool_response_writes_large_body_to_disk(tmp_path):
+    """閾値を超えたボディは一時ファイルに退避し、ストリームで読み出せる"""
+    body = b"x" * 200_000
+    respx.post(RIE_URL).mock(return_value=httpx.Response(200, content=body))
+
+    async with httpx.AsyncClient() as client:
+        result = await spool_response(
+            "func", await _stream(client), threshold=1024, directory=str(tmp_path)
+        )
+
+    assert is_spooled(result)
+    assert result.headers["content-length"] == str(len(body))
+
+    chunks = [chunk async for chunk in result.aiter_raw()]
+    assert b"".join(chunks) == body
+    assert result.stream.file.closed
+
+
+@pytest.mark.asyncio
+@respx.mock
+async def test_spool_response_rejects_body_over_limit():
+    """max_bytes を超えるボディは PayloadTooLargeError"""
+    respx.post(RIE_URL).mock(return_value=httpx.Response(200, content=b"x" * 4096))
+
+    async with httpx.AsyncClient() as client:
+        with pytest.raises(PayloadTooLargeError) as exc_info:
+            await spool_response("func", await _stream(client), threshold=1024, max_bytes=2048)
+
+    assert exc_info.value.direction == "response"
+    assert exc_info.value.limit == 2048
+
+
+@pytest.mark.asyncio
+@respx.mock
+async def test_spool_response_without_threshold_keeps_body_in_memory(tmp_path):
+    """threshold=0 の場合は退避せず、上限内のボディをメモリ上に保持する"""
+    body = b"x" * 200_000
+    respx.post(RIE_URL).mock(return_value=httpx.Response(200, content=body))
+
+    async with httpx.AsyncClient() as client:
+        result = await spool_response(
+            "func", await _stream(client), threshold=0, max_bytes=len(body), directory=str(tmp_path)
+        )
+
+    assert not is_spooled(result)
+    assert result.content == body
+    assert list(tmp_path.iterdir()) == []
+
+
+@pytest.mark.asyncio
+async def test_spooled_stream_close_releases_file():
+    """aclose で退避ファイルを閉じる"""
+    file = MagicMock()
+    stream = SpooledResponseStream(file)
+
+    await stream.aclose()
+
+    file.close.assert_called_once()
+
+
+@pytest.mark.asyncio
+@respx.mock
+async def test_invoker_spools_large_response():
+    """RESPONSE_SPOOL_THRESHOLD_BYTES が設定されている場合はストリームで受信して退避する"""
+    registry = MagicMock(spec=FunctionRegistry)
+    registry.get_function_config.return_value = {"image": "img", "environment": {}}
+    container_manager = AsyncMock()
+    container_manager.get_lambda_host.return_value = "10.0.0.1"
+    config = GatewayConfig(LAMBDA_PORT=8080, RESPONSE_SPOOL_THRESHOLD_BYTES=1024)
+
+    body = b"y" * 100_000
+    respx.post(RIE_URL).mock(return_value=httpx.Response(200, content=body))
+
+    async with httpx.AsyncClient() as client:
+        invoker = LambdaInvoker(client, registry, container_manager, config)
+        response = await invoker.invoke_function("func", b"{}")
+
+        assert is_spooled(response)
+        assert await response.aread() == body
+
+
+@pytest.mark.asyncio
+@respx.mock
+async def test_invoker_rejects_response_over_limit():
+    """MAX_RESPONSE_PAYLOAD_BYTES を超えるレスポンスは PayloadTooLargeError"""
+    registry = MagicMock(spec=FunctionRegistry)
+    registry.get_function_config.return_value = {"image": "img", "environment": {}}
+    container_manager = AsyncMock()
+    container_manager.get_lambda_host.return_value = "10.0.0.1"
+    config = GatewayConfig(LAMBDA_PORT=8080, MAX_RESPONSE_PAYLOAD_BYTES=1024)
+
+    respx.post(RIE_URL).mock(return_value=httpx.Response(200, content=b"z" * 2048))
+
+    async with httpx.AsyncClient() as client:
+        invoker = LambdaInvoker(client, registry, container_manager, config)
+        with pytest.raises(PayloadTooLargeError):
+            await invoker.invoke_function("func", b"{}")
+
+        # 上限超過はバックエンドの障害ではないため、ブレーカーの失敗として数えない
+        assert invoker.breakers["func"].failures == 0
@@ -27,3 +27,12 @@ def test_parse_lambda_response_logs_warning_on_invalid_json_body():
 
         # 結果は元の文字列のまま返される
         assert result["content"] == "{invalid json here"
+
+
+def test_parse_deadline_header():
+    """X-Deadline (エポックミリ秒) をエポック秒に変換し、不正値は None とする"""
+    from services.gateway.core.utils import parse_deadline_header
+
+    assert parse_deadline_header("1700000000500") == 1700000000.5
+    assert parse_deadline_header(None) is None
+    assert parse_deadline_header("not-a-number") is None
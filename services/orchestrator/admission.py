@@ -0,0 +1,153 @@
+"""
+Admission Control
+
+ホストの容量 (/proc と cgroup ルートから取得) から稼働中ワーカーの予約分を差し引き、
+オーバーコミット率を超えるコンテナ作成要求を拒否します。
+"""
+
+import logging
+import os
+from dataclasses import dataclass
+from typing import Dict, Optional
+
+logger = logging.getLogger("orchestrator.admission")
+
+# cgroup v2 の "max" / v1 の巨大値は無制限として扱う
+_CGROUP_V1_UNLIMITED = 1 << 62
+
+
+@dataclass
+class HostCapacity:
+    """ホストで利用可能なリソース"""
+
+    memory_bytes: int
+    cpus: float
+
+
+class AdmissionRejectedError(Exception):
+    """容量不足によるコンテナ作成拒否の例外"""
+
+    def __init__(self, function_name: str, requested: int, shortfall: Dict[str, float]):
+        self.function_name = function_name
+        self.requested = requested
+        self.shortfall = shortfall
+        super().__init__(
+            f"Insufficient host capacity for {requested} container(s) of {function_name}: "
+            f"shortfall {shortfall}"
+        )
+
+
+def _read_first_line(path: str) -> Optional[str]:
+    try:
+        with open(path) as f:
+            return f.readline().strip()
+    except OSError:
+        return None
+
+
+def _read_mem_total(proc_root: str) -> int:
+    """/proc/meminfo の MemTotal (bytes)"""
+    try:
+        with open(os.path.join(proc_root, "meminfo")) as f:
+            for line in f:
+                if line.startswith("MemTotal:"):
+                    return int(line.split()[1]) * 1024
+    except OSError:
+        pass
+    return 0
+
+
+def _read_cgroup_memory_limit(cgroup_root: str) -> Optional[int]:
+    """cgroup のメモリ上限 (v2: memory.max, v1: memory.limit_in_bytes)"""
+    for path in (
+        os.path.join(cgroup_root, "memory.max"),
+        os.path.join(cgroup_root, "memory", "memory.limit_in_bytes"),
+    ):
+        value = _read_first_line(path)
+        if value is None or value == "max":
+            continue
+        limit = int(value)
+        if limit < _CGROUP_V1_UNLIMITED:
+            return limit
+    return None
+
+
+def _read_cgroup_cpu_limit(cgroup_root: str) -> Optional[float]:
+    """cgroup の CPU 上限 (v2: cpu.max, v1: cpu.cfs_quota_us / cpu.cfs_period_us)"""
+    value = _read_first_line(os.path.join(cgroup_root, "cpu.max"))
+    if value:
+        quota, _, period = value.partition(" ")
+        if quota != "max" and period:
+            return int(quota) / int(period)
+
+    quota = _read_first_line(os.path.join(cgroup_root, "cpu", "cpu.cfs_quota_us"))
+    period = _read_first_line(os.path.join(cgroup_root, "cpu", "cpu.cfs_period_us"))
+    if quota and period and int(quota) > 0:
+        return int(quota) / int(period)
+    return None
+
+
+def read_host_capacity(
+    proc_root: str = "/proc", cgroup_root: str = "/sys/fs/cgroup"
+) -> HostCapacity:
+    """
+    ホスト容量を取得
+
+    /proc の値と cgroup ルートの上限のうち小さい方を採用します。
+    """
+    memory = _read_mem_total(proc_root)
+    cgroup_memory = _read_cgroup_memory_limit(cgroup_root)
+    if cgroup_memory is not None:
+        memory = min(memory, cgroup_memory) if memory else cgroup_memory
+
+    cpus = float(os.cpu_count() or 1)
+    cgroup_cpus = _read_cgroup_cpu_limit(cgroup_root)
+    if cgroup_cpus is not None:
+        cpus = min(cpus, cgroup_cpus)
+
+    return HostCapacity(memory_bytes=memory, cpus=cpus)
+
+
+class AdmissionController:
+    """
+    ワーカー単位の予約量でホスト容量を管理する
+    """
+
+    def __init__(
+        self,
+        capacity: HostCapacity,
+        memory_per_worker: int,
+        cpus_per_worker: float,
+        overcommit_factor: float = 1.0,
+    ):
+        self.capacity = capacity
+        self.memory_per_worker = memory_per_worker
+        self.cpus_per_worker = cpus_per_worker
+        self.overcommit_factor = overcommit_factor
+
+    def shortfall(self, running: int, requested: int = 1) -> Dict[str, float]:
+        """requested 個を追加した場合の不足量（不足がなければ空）"""
+        workers = running + requested
+        result: Dict[str, float] = {}
+
+        memory_limit = self.capacity.memory_bytes * self.overcommit_factor
+        memory_needed = workers * self.memory_per_worker
+        if self.memory_per_worker and memory_needed > memory_limit:
+            result["memory_bytes"] = int(memory_needed - memory_limit)
+
+        cpu_limit = self.capacity.cpus * self.overcommit_factor
+        cpu_needed = workers * self.cpus_per_worker
+        if self.cpus_per_worker and cpu_needed > cpu_limit:
+            result["cpus"] = round(cpu_needed - cpu_limit, 3)
+
+        return result
+
+    def admit(self, function_name: str, running: int, requested: int = 1) -> None:
+        """容量を超える場合は AdmissionRejectedError を送出"""
+        shortfall = self.shortfall(running, requested)
+        if shortfall:
+            logger.warning(
+                f"Admission rejected for {function_name} "
+                f"(running: {running}, requested: {requested}, shortfall: {shortfall})"
+            )
+            raise AdmissionRejectedError(function_name, requested, shortfall)
@@ -0,0 +1,24 @@
+"""
+External Container Adoption
+
+nerdctl や docker CLI などで手動起動したコンテナのうち、採用ラベルを持つものを管理下に取り込みます。
+採用したコンテナはアクセス記録 (アイドル停止の対象) とコンテナ一覧 (Gateway の Adoption) に
+含まれるため、手動運用と自動運用が混在しても把握できないワーカーが残りません。
+
+外部から起動する場合は以下の規約に従ってください (--label / --name は nerdctl でも同じ指定です)。
+
+    nerdctl run -d --name lambda-echo-manual1 --network <CONTAINERS_NETWORK> \\
+        --label esb.adopt=true --label function_name=lambda-echo <image>
+
+- 名前: lambda-{関数名}-{サフィックス} (Gateway はコンテナ名から関数を判別します)
+- ラベル: EXTERNAL_ADOPTION_LABEL (デフォルト: esb.adopt=true)。関数名は function_name ラベル
+"""
+
+CONTAINER_NAME_PREFIX = "lambda-"
+
+
+def follows_naming_convention(name: str) -> bool:
+    """コンテナ名が lambda-{関数名}-{サフィックス} 形式か"""
+    if not name.startswith(CONTAINER_NAME_PREFIX):
+        return False
+    return len(name.split("-")) >= 3
@@ -0,0 +1,90 @@
+"""
+コンテナの時刻・タイムゾーン設定
+
+Lambda イメージはデフォルトで UTC のため、必要に応じて以下を注入します。
+
+- TZ 環境変数 (CONTAINER_TIMEZONE: IANA 名、または "host" でホスト設定から検出)
+- /etc/localtime の読み取り専用バインド (CONTAINER_MOUNT_LOCALTIME)
+- PTP ハードウェアクロックなどの時刻参照デバイス (CONTAINER_CLOCK_DEVICES)
+"""
+
+import logging
+import os
+from typing import Any, Dict, List, Mapping, Optional
+
+from .config import config
+
+logger = logging.getLogger("orchestrator.clock")
+
+# CONTAINER_TIMEZONE でホストの設定を使用する指定
+HOST_TIMEZONE = "host"
+# 時刻参照デバイスのパスを関数に伝える環境変数
+CLOCK_DEVICE_ENV = "ESB_CLOCK_DEVICE"
+
+LOCALTIME_PATH = "/etc/localtime"
+
+
+def detect_host_timezone(
+    environ: Optional[Mapping[str, str]] = None, etc_root: str = "/etc"
+) -> Optional[str]:
+    """
+    ホストのタイムゾーンを検出
+
+    TZ 環境変数、/etc/timezone、/etc/localtime のリンク先 (zoneinfo/Area/City) の順に参照し、
+    いずれからも取得できない場合は None を返します。
+    """
+    environ = os.environ if environ is None else environ
+    tz = environ.get("TZ", "").lstrip(":")
+    if tz:
+        return tz
+
+    try:
+        with open(os.path.join(etc_root, "timezone")) as f:
+            tz = f.readline().strip()
+        if tz:
+            return tz
+    except OSError:
+        pass
+
+    try:
+        target = os.readlink(os.path.join(etc_root, "localtime"))
+    except OSError:
+        return None
+    _, sep, zone = target.partition("zoneinfo/")
+    return zone if sep and zone else None
+
+
+def resolve_timezone(setting: Optional[str]) -> Optional[str]:
+    """CONTAINER_TIMEZONE の値から注入するタイムゾーンを決定"""
+    if not setting:
+        return None
+    if setting.lower() != HOST_TIMEZONE:
+        return setting
+
+    tz = detect_host_timezone()
+    if tz is None:
+        logger.warning("CONTAINER_TIMEZONE=host but host timezone could not be detected")
+    return tz
+
+
+def clock_environment() -> Dict[str, str]:
+    """時刻関連のデフォルト環境変数 (関数設定で上書き可能)"""
+    env: Dict[str, str] = {}
+    tz = resolve_timezone(config.CONTAINER_TIMEZONE)
+    if tz:
+        env["TZ"] = tz
+    if config.CONTAINER_CLOCK_DEVICES:
+        env[CLOCK_DEVICE_ENV] = config.CONTAINER_CLOCK_DEVICES[0]
+    return env
+
+
+def build_clock_options() -> Dict[str, Any]:
+    """run_container に渡す /etc/localtime のバインドと時刻参照デバイスの引数"""
+    options: Dict[str, Any] = {}
+    if config.CONTAINER_MOUNT_LOCALTIME:
+        options["volumes"] = {LOCALTIME_PATH: {"bind": LOCALTIME_PATH, "mode": "ro"}}
+
+    devices: List[str] = [f"{path}:{path}:r" for path in config.CONTAINER_CLOCK_DEVICES]
+    if devices:
+        options["devices"] = devices
+    return options
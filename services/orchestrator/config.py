@@ -3,6 +3,7 @@ Orchestrator設定定義
 """
 
 import sys
+from typing import Any, Dict, List, Literal, Optional
 from pydantic import Field
 from services.common.core.config import BaseAppConfig
 
@@ -29,6 +30,144 @@ class OrchestratorConfig(BaseAppConfig):
     DOCKER_CLIENT_TIMEOUT: int = Field(
         default=60, description="Dockerクライアントの通信タイムアウト(秒)"
     )
+    CONTAINER_STOP_GRACE_PERIOD: int = Field(
+        default=10, description="停止時に SIGTERM 送信後 SIGKILL までの猶予期間(秒)"
+    )
+    CONTAINER_DEFAULT_ENV: Dict[str, str] = Field(
+        default_factory=dict,
+        description="全コンテナ共通のデフォルト環境変数 (JSON、関数設定で上書き可能)",
+    )
+    CONTAINER_DEFAULT_USER: Optional[str] = Field(
+        default=None, description="コンテナの実行ユーザー (未指定時はイメージのデフォルト)"
+    )
+    CONTAINER_DEFAULT_CAPABILITIES: List[str] = Field(
+        default=["CHOWN", "DAC_OVERRIDE", "FOWNER", "SETGID", "SETUID", "NET_BIND_SERVICE"],
+        description="コンテナに付与する capability (それ以外はすべて破棄)",
+    )
+    CONTAINER_TIMEZONE: Optional[str] = Field(
+        default=None,
+        description="コンテナに設定する TZ (IANA 名、または host でホスト設定を使用)",
+    )
+    CONTAINER_MOUNT_LOCALTIME: bool = Field(
+        default=False, description="Docker ホストの /etc/localtime を読み取り専用でバインド"
+    )
+    CONTAINER_CLOCK_DEVICES: List[str] = Field(
+        default_factory=list,
+        description="コンテナに渡す時刻参照デバイス (例: /dev/ptp0、先頭を ESB_CLOCK_DEVICE に設定)",
+    )
+    CONTAINER_PLATFORM: Optional[str] = Field(
+        default=None,
+        description="イメージ取得・コンテナ起動時のデフォルトプラットフォーム (例: linux/arm64)",
+    )
+
+    # OOM 検出と推奨メモリ算出
+    OOM_RESTART_POLICY: Literal["restart", "recreate"] = Field(
+        default="restart",
+        description="OOM Kill されたコンテナの再起動方針 (restart: 再始動, recreate: 再作成)",
+    )
+    MEMORY_STATS_INTERVAL_SECONDS: int = Field(
+        default=60, description="メモリ使用量のサンプリング間隔(秒)"
+    )
+    MEMORY_SUGGESTION_HEADROOM: float = Field(
+        default=1.5, description="推奨メモリ算出時にピーク使用量へ掛ける余裕率"
+    )
+
+    # オフライン環境向けイメージアーカイブ読み込み
+    IMAGE_ARCHIVE_DIR: str = Field(
+        default="/data/images",
+        description="パス指定でのイメージアーカイブ読み込みを許可するディレクトリ",
+    )
+    IMAGE_ARCHIVE_MAX_BYTES: int = Field(
+        default=10 * 1024 * 1024 * 1024,
+        description="アップロードされたイメージアーカイブの上限(bytes、0 は無制限)",
+    )
+
+    # 関数ごとの永続スクラッチボリューム
+    VOLUME_ROOT: Optional[str] = Field(
+        default=None,
+        description="関数ボリュームの保存先ルート (未指定時は Docker 管理領域)",
+    )
+    VOLUME_HOST_ROOT: Optional[str] = Field(
+        default=None,
+        description="Docker ホストから見た VOLUME_ROOT のパス (未指定時は同じパス)",
+    )
+    VOLUME_USAGE_INTERVAL_SECONDS: int = Field(
+        default=300, description="ボリューム使用量の計測間隔(秒)"
+    )
+    DEV_CODE_ROOT: Optional[str] = Field(
+        default=None,
+        description="開発用: 関数コードのルート (設定時はバインドして変更を監視)",
+    )
+    DEV_CODE_HOST_ROOT: Optional[str] = Field(
+        default=None,
+        description="開発用: Docker ホストから見た DEV_CODE_ROOT のパス (未指定時は同じパス)",
+    )
+    DEV_CODE_MOUNT_PATH: str = Field(
+        default="/var/task", description="開発用: コンテナ内のコード配置先"
+    )
+    EVICTION_ENABLED: bool = Field(
+        default=False,
+        description="容量不足時に再起動コストの低いアイドルコンテナを停止して受け入れる",
+    )
+    EVICTION_MIN_IDLE_SECONDS: int = Field(
+        default=120,
+        description="容量不足時の停止対象とする最小アイドル時間(秒、Heartbeat 間隔より長くする)",
+    )
+    EVICTION_COLD_START_WEIGHT: float = Field(
+        default=1.0, description="停止スコアにおけるコールドスタート秒数の重み"
+    )
+    EVICTION_IMAGE_WEIGHT: float = Field(
+        default=0.5, description="停止スコアにおけるイメージサイズ (GB) の重み"
+    )
+    LEASE_RECLAIM_INTERVAL_SECONDS: int = Field(
+        default=5, description="期限切れリースのコンテナを回収する間隔(秒)"
+    )
+
+    # 外部で起動したコンテナの取り込み (nerdctl / docker CLI での手動起動)
+    EXTERNAL_ADOPTION_ENABLED: bool = Field(
+        default=False, description="採用ラベルを持つ外部コンテナを管理下に取り込む"
+    )
+    EXTERNAL_ADOPTION_LABEL: str = Field(
+        default="esb.adopt=true", description="取り込み対象とするラベル (key=value または key)"
+    )
+    EXTERNAL_ADOPTION_INTERVAL_SECONDS: int = Field(
+        default=30, description="外部コンテナを検出する間隔(秒)"
+    )
+
+    # 障害注入 (テスト・ステージング用)
+    FAULT_INJECTION_ENABLED: bool = Field(
+        default=False, description="Docker 操作への障害注入を有効化 (本番では無効にすること)"
+    )
+    FAULT_INJECTION_RULES: List[Dict[str, Any]] = Field(
+        default_factory=list,
+        description='障害注入ルール (JSON 例: [{"operation": "run_container", "action": "partial"}])',
+    )
+    FAULT_INJECTION_SEED: int = Field(default=0, description="障害の発生判定に使う乱数の seed")
+
+    # クラッシュループ検出
+    CRASH_LOOP_THRESHOLD: int = Field(
+        default=5, description="隔離と判定する異常終了回数 (ウィンドウ内)"
+    )
+    CRASH_LOOP_WINDOW_SECONDS: float = Field(
+        default=60.0, description="異常終了回数を集計するウィンドウ(秒)"
+    )
+    QUARANTINE_COOLDOWN_SECONDS: float = Field(
+        default=300.0, description="隔離を自動解除するまでの時間(秒)"
+    )
+
+    # ホスト容量に基づくアドミッション制御
+    ADMISSION_CONTROL_ENABLED: bool = Field(
+        default=False, description="ホスト容量を超えるコンテナ作成要求を拒否する"
+    )
+    ADMISSION_OVERCOMMIT_FACTOR: float = Field(
+        default=1.0, gt=0, description="ホスト容量に対して許容する予約量の倍率"
+    )
+    WORKER_MEMORY_RESERVATION_MB: int = Field(
+        default=128, ge=0, description="ワーカー1つあたりのメモリ予約量(MB)"
+    )
+    WORKER_CPU_RESERVATION: float = Field(
+        default=0.25, ge=0, description="ワーカー1つあたりの CPU 予約量(コア)"
+    )
 
 
 # シングルトンとして設定をロード
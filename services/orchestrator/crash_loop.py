@@ -0,0 +1,101 @@
+"""
+Crash Loop Detector
+
+短時間に異常終了を繰り返す関数を検出し、隔離 (QUARANTINED) 状態にします。
+隔離中の関数はコンテナを起動せずに即座にエラーとし、再起動の嵐からホストを保護します。
+"""
+
+import logging
+import time
+from collections import deque
+from dataclasses import dataclass
+from typing import Deque, Dict, Optional
+
+logger = logging.getLogger("orchestrator.crash_loop")
+
+# 関数状態: クラッシュループにより隔離中
+QUARANTINED = "QUARANTINED"
+
+# オーケストレーター自身の停止 (SIGTERM / SIGKILL) や正常終了による終了コード
+NON_CRASH_EXIT_CODES = {0, 137, 143}
+
+
+class FunctionQuarantinedError(Exception):
+    """隔離中の関数に対する起動要求の例外"""
+
+    def __init__(self, function_name: str, crashes: int, until: float):
+        self.function_name = function_name
+        self.crashes = crashes
+        self.until = until
+        remaining = max(0, int(until - time.time()))
+        super().__init__(
+            f"Function {function_name} is {QUARANTINED} after {crashes} crashes "
+            f"(retry in {remaining}s or clear the quarantine)"
+        )
+
+
+@dataclass
+class QuarantineEntry:
+    crashes: int
+    since: float
+    until: float
+
+
+def is_crash_exit_code(exit_code: Optional[int]) -> bool:
+    """異常終了とみなす終了コードかを判定"""
+    return exit_code is not None and exit_code not in NON_CRASH_EXIT_CODES
+
+
+class CrashLoopDetector:
+    """
+    関数ごとの異常終了をスライディングウィンドウで集計する
+    """
+
+    def __init__(self, threshold: int = 5, window_seconds: float = 60.0, cooldown: float = 300.0):
+        self.threshold = threshold
+        self.window_seconds = window_seconds
+        self.cooldown = cooldown
+        self._crashes: Dict[str, Deque[float]] = {}
+        self._quarantined: Dict[str, QuarantineEntry] = {}
+
+    def record_crash(self, function_name: str) -> None:
+        """異常終了を記録し、しきい値に達したら隔離する"""
+        now = time.time()
+        crashes = self._crashes.setdefault(function_name, deque())
+        crashes.append(now)
+        while crashes and now - crashes[0] > self.window_seconds:
+            crashes.popleft()
+
+        if len(crashes) >= self.threshold and function_name not in self._quarantined:
+            self._quarantined[function_name] = QuarantineEntry(
+                crashes=len(crashes), since=now, until=now + self.cooldown
+            )
+            logger.error(
+                f"Function {function_name} crashed {len(crashes)} times within "
+                f"{self.window_seconds}s, quarantined for {self.cooldown}s"
+            )
+
+    def check(self, function_name: str) -> None:
+        """隔離中であれば FunctionQuarantinedError を送出（期限切れなら自動解除）"""
+        entry = self._quarantined.get(function_name)
+        if entry is None:
+            return
+        if time.time() >= entry.until:
+            logger.info(f"Quarantine expired for {function_name}")
+            self.clear(function_name)
+            return
+        raise FunctionQuarantinedError(function_name, entry.crashes, entry.until)
+
+    def clear(self, function_name: str) -> bool:
+        """隔離を解除し、クラッシュ履歴をリセット。隔離中だった場合は True"""
+        self._crashes.pop(function_name, None)
+        return self._quarantined.pop(function_name, None) is not None
+
+    def snapshot(self) -> Dict[str, Dict[str, float]]:
+        """隔離中の関数一覧"""
+        now = time.time()
+        return {
+            name: {"crashes": e.crashes, "since": e.since, "until": e.until}
+            for name, e in self._quarantined.items()
+            if now < e.until
+        }
@@ -5,9 +5,11 @@ Blocking docker-py calls are offloaded to a dedicated executor.
 """
 
 import asyncio
+import contextlib
 import docker
 import logging
-from typing import Any, List
+import threading
+from typing import Any, Awaitable, Callable, Dict, List, Optional
 from concurrent.futures import ThreadPoolExecutor
 from importlib.metadata import metadata
 
@@ -18,6 +20,34 @@ logger = logging.getLogger("manager.docker_adaptor")
 # ラベル用の短縮名（ブランド統一）
 PROJECT_LABEL = "esb"
 
+# アーキテクチャ名の別名 (uname 表記 -> OCI 表記)
+_ARCH_ALIASES = {"x86_64": "amd64", "aarch64": "arm64"}
+
+# イベントストリームの終了をキューで通知するためのマーカー
+_EVENT_STREAM_CLOSED = object()
+
+
+class ImagePlatformError(Exception):
+    """要求されたプラットフォーム向けのイメージが存在しない場合の例外"""
+
+    def __init__(self, image: str, platform: str, detail: str = ""):
+        self.image = image
+        self.platform = platform
+        message = f"Image {image} is not available for platform {platform}"
+        if detail:
+            message = f"{message}: {detail}"
+        super().__init__(message)
+
+
+def _parse_platform(platform: str) -> tuple[str, str]:
+    """'os/arch[/variant]' を (os, arch) に分解（arch は OCI 表記に正規化）"""
+    parts = platform.lower().split("/")
+    if len(parts) == 1:
+        os_name, arch = "linux", parts[0]
+    else:
+        os_name, arch = parts[0], parts[1]
+    return os_name, _ARCH_ALIASES.get(arch, arch)
+
 
 class DockerAdaptor:
     def __init__(self):
@@ -41,6 +71,59 @@ class DockerAdaptor:
             self.executor, lambda: self._client.containers.run(image, **kwargs)
         )
 
+    async def ensure_image(
+        self, image: str, platform: Optional[str] = None, pull: bool = False
+    ) -> Any:
+        """
+        イメージがローカルに存在することを保証し、必要であれば pull する。
+
+        platform が指定された場合は該当プラットフォーム向けに pull し、
+        ローカルのイメージのアーキテクチャが一致しない場合は ImagePlatformError を送出する。
+        pull=True の場合はローカルに存在しても pull し、同じタグの更新を取り込む
+        (レジストリに接続できない場合はローカルのイメージを使用)。
+        """
+
+        def _ensure():
+            local = None
+            try:
+                local = self._client.images.get(image)
+                if platform is not None and not self._matches_platform(local, platform):
+                    logger.info(f"Local image {image} does not match {platform}, pulling...")
+                    local = None
+                elif not pull:
+                    return local
+            except docker.errors.ImageNotFound:
+                logger.info(f"Pulling image {image} (platform: {platform or 'default'})")
+
+            try:
+                img = self._client.images.pull(image, platform=platform)
+            except docker.errors.APIError as e:
+                # マルチアーキテクチャのマニフェストに該当プラットフォームがない
+                if platform and "no matching manifest" in str(e.explanation or e):
+                    raise ImagePlatformError(image, platform, str(e.explanation or e)) from e
+                if local is not None:
+                    logger.warning(f"Failed to pull {image}, using local image: {e}")
+                    return local
+                raise
+
+            if platform is not None and not self._matches_platform(img, platform):
+                raise ImagePlatformError(
+                    image,
+                    platform,
+                    f"pulled image is {img.attrs.get('Os')}/{img.attrs.get('Architecture')}",
+                )
+            return img
+
+        loop = asyncio.get_running_loop()
+        return await loop.run_in_executor(self.executor, _ensure)
+
+    @staticmethod
+    def _matches_platform(img: Any, platform: str) -> bool:
+        os_name, arch = _parse_platform(platform)
+        img_os = (img.attrs.get("Os") or "linux").lower()
+        img_arch = (img.attrs.get("Architecture") or "").lower()
+        return img_os == os_name and _ARCH_ALIASES.get(img_arch, img_arch) == arch
+
     async def list_containers(self, **kwargs) -> List[Any]:
         loop = asyncio.get_running_loop()
         return await loop.run_in_executor(
@@ -55,9 +138,23 @@ class DockerAdaptor:
         loop = asyncio.get_running_loop()
         await loop.run_in_executor(self.executor, container.reload)
 
-    async def stop_container(self, container: Any) -> None:
+    async def stop_container(self, container: Any, timeout: Optional[int] = None) -> None:
+        """
+        SIGTERM を送信し、猶予期間内に終了しなければ SIGKILL で停止
+
+        timeout 未指定時は CONTAINER_STOP_GRACE_PERIOD を使用します。
+        """
+        if timeout is None:
+            timeout = config.CONTAINER_STOP_GRACE_PERIOD
         loop = asyncio.get_running_loop()
-        await loop.run_in_executor(self.executor, container.stop)
+        await loop.run_in_executor(self.executor, lambda: container.stop(timeout=timeout))
+
+    async def restart_container(self, container: Any, timeout: Optional[int] = None) -> None:
+        """コンテナを再起動（停止の猶予期間は stop_container と同じ）"""
+        if timeout is None:
+            timeout = config.CONTAINER_STOP_GRACE_PERIOD
+        loop = asyncio.get_running_loop()
+        await loop.run_in_executor(self.executor, lambda: container.restart(timeout=timeout))
 
     async def remove_container(self, container: Any, force: bool = False) -> None:
         loop = asyncio.get_running_loop()
@@ -67,6 +164,162 @@ class DockerAdaptor:
         loop = asyncio.get_running_loop()
         await loop.run_in_executor(self.executor, container.kill)
 
+    async def pause_container(self, container: Any) -> None:
+        loop = asyncio.get_running_loop()
+        await loop.run_in_executor(self.executor, container.pause)
+
+    async def unpause_container(self, container: Any) -> None:
+        loop = asyncio.get_running_loop()
+        await loop.run_in_executor(self.executor, container.unpause)
+
+    async def load_image_archive(self, archive_path: str) -> List[str]:
+        """
+        docker save / OCI 形式の tar アーカイブからイメージを読み込む
+
+        読み込まれたイメージのタグ一覧を返します（タグなしの場合は ID）。
+        """
+
+        def _load():
+            with open(archive_path, "rb") as f:
+                images = self._client.images.load(f)
+            return [tag for img in images for tag in (img.tags or [img.id])]
+
+        loop = asyncio.get_running_loop()
+        return await loop.run_in_executor(self.executor, _load)
+
+    async def get_image_id(self, image: str) -> Optional[str]:
+        """ローカルイメージの ID (存在しない場合は None)"""
+
+        def _get():
+            try:
+                return self._client.images.get(image).id
+            except docker.errors.ImageNotFound:
+                return None
+
+        loop = asyncio.get_running_loop()
+        return await loop.run_in_executor(self.executor, _get)
+
+    async def get_image_size(self, image: str) -> int:
+        """ローカルイメージのサイズ (bytes)"""
+        loop = asyncio.get_running_loop()
+        img = await loop.run_in_executor(self.executor, lambda: self._client.images.get(image))
+        return int(img.attrs.get("Size") or 0)
+
+    async def ensure_volume(
+        self,
+        name: str,
+        labels: Optional[Dict[str, str]] = None,
+        driver_opts: Optional[Dict[str, str]] = None,
+    ) -> Any:
+        """名前付きボリュームを取得し、存在しなければ作成"""
+
+        def _ensure():
+            try:
+                return self._client.volumes.get(name)
+            except docker.errors.NotFound:
+                logger.info(f"Creating volume {name}")
+                return self._client.volumes.create(
+                    name=name, driver="local", driver_opts=driver_opts or {}, labels=labels or {}
+                )
+
+        loop = asyncio.get_running_loop()
+        return await loop.run_in_executor(self.executor, _ensure)
+
+    async def get_volume_usage(self, label: str) -> Dict[str, int]:
+        """
+        指定ラベルを持つボリュームの使用量 (bytes) をラベル値ごとに取得
+
+        docker system df を使用するため、定期ジョブ程度の頻度で呼び出してください。
+        """
+
+        def _usage():
+            volumes = self._client.df().get("Volumes") or []
+            return {
+                v["Labels"][label]: int((v.get("UsageData") or {}).get("Size") or 0)
+                for v in volumes
+                if label in (v.get("Labels") or {})
+            }
+
+        loop = asyncio.get_running_loop()
+        return await loop.run_in_executor(self.executor, _usage)
+
+    async def get_container_stats(self, container: Any) -> Dict[str, Any]:
+        """コンテナのリソース統計を1回分取得"""
+        loop = asyncio.get_running_loop()
+        return await loop.run_in_executor(
+            self.executor, lambda: container.stats(stream=False, one_shot=True)
+        )
+
+    async def listen_events(
+        self,
+        handler: Callable[[Dict[str, Any]], Awaitable[None]],
+        filters: Optional[Dict[str, Any]] = None,
+        reconnect_delay: float = 1.0,
+        max_reconnect_delay: float = 30.0,
+    ) -> None:
+        """
+        Docker イベントを購読し、受信したイベントごとに handler を呼び出します。
+
+        イベントストリームは無期限にブロックするため、スレッドプールではなく
+        専用のデーモンスレッドで読み出し、キュー経由でイベントループに渡します。
+        ストリームが切断された場合 (Docker デーモンの再起動など) は指数バックオフで
+        再接続します。切断中に発生したイベントは受信できません。
+        タスクがキャンセルされるまで戻りません。
+        """
+        loop = asyncio.get_running_loop()
+        delay = reconnect_delay
+
+        while True:
+            queue: asyncio.Queue = asyncio.Queue()
+            streams: List[Any] = []
+            threading.Thread(
+                target=self._pump_events,
+                args=(loop, queue, filters, streams),
+                name="docker_events",
+                daemon=True,
+            ).start()
+
+            try:
+                while True:
+                    event = await queue.get()
+                    if event is _EVENT_STREAM_CLOSED:
+                        break
+                    # イベントを受信できていれば接続は正常とみなしバックオフを戻す
+                    delay = reconnect_delay
+                    try:
+                        await handler(event)
+                    except Exception as e:
+                        logger.error(f"Error handling docker event: {e}", exc_info=True)
+            finally:
+                # キャンセル時にブロック中の読み出しスレッドを解放する
+                for stream in streams:
+                    stream.close()
+
+            logger.warning(f"Reconnecting to Docker event stream in {delay:.1f}s")
+            await asyncio.sleep(delay)
+            delay = min(delay * 2, max_reconnect_delay)
+
+    def _pump_events(
+        self,
+        loop: asyncio.AbstractEventLoop,
+        queue: asyncio.Queue,
+        filters: Optional[Dict[str, Any]],
+        streams: List[Any],
+    ) -> None:
+        """イベントストリームを読み出してキューに渡し、終了時に終端マーカーを積む"""
+        try:
+            stream = self._client.events(decode=True, filters=filters)
+            streams.append(stream)
+            for event in stream:
+                loop.call_soon_threadsafe(queue.put_nowait, event)
+            logger.warning("Docker event stream closed")
+        except Exception as e:
+            logger.error(f"Docker event stream terminated: {e}")
+        finally:
+            # イベントループ終了後はマーカーを渡す先がないため無視する
+            with contextlib.suppress(RuntimeError):
+                loop.call_soon_threadsafe(queue.put_nowait, _EVENT_STREAM_CLOSED)
+
     async def prune_containers(self) -> None:
         """
         ゾンビコンテナ（label=created_by={PROJECT_LABEL}）を削除します。
@@ -0,0 +1,56 @@
+"""
+コンテナ環境変数の合成
+
+以下の順にレイヤーを重ね、後のレイヤーが優先されます。
+
+1. Orchestrator 全体のデフォルト (CONTAINER_DEFAULT_ENV: リージョン、プロキシ設定など、
+   および TZ などの時刻設定)
+2. 関数ごとの設定 (Gateway が functions.yml から解決した environment)
+3. 起動要求ごとの上書き (env_overrides)
+4. Orchestrator が管理するシステム変数 (上書き不可)
+"""
+
+import os
+import re
+from typing import Dict, Optional
+
+from .clock import clock_environment
+from .config import config
+
+# ログ出力時に値を伏せるキーのパターン
+SECRET_KEY_PATTERN = re.compile(r"SECRET|PASSWORD|PASSWD|TOKEN|CREDENTIAL|PRIVATE|KEY", re.I)
+REDACTED = "***"
+
+
+def system_environment(function_name: str) -> Dict[str, str]:
+    """Orchestrator が常に注入するシステム変数"""
+    vl_host = os.environ.get("VICTORIALOGS_HOST", "victorialogs")
+    vl_port = os.environ.get("VICTORIALOGS_PORT", "9428")
+    return {
+        # Prevent log buffering
+        "PYTHONUNBUFFERED": "1",
+        # VictoriaLogs URL を Lambda に注入（直接ログ送信用）
+        "VICTORIALOGS_URL": f"http://{vl_host}:{vl_port}/insert/jsonline",
+        # AWS Lambda 互換環境変数（sitecustomize.py での動的ログ処理に使用）
+        "AWS_LAMBDA_FUNCTION_NAME": function_name,
+    }
+
+
+def resolve_environment(
+    function_name: str,
+    function_env: Optional[Dict[str, str]] = None,
+    overrides: Optional[Dict[str, str]] = None,
+) -> Dict[str, str]:
+    """各レイヤーを優先順位に従って合成した環境変数を返す"""
+    env: Dict[str, str] = {}
+    env.update(config.CONTAINER_DEFAULT_ENV)
+    env.update(clock_environment())
+    env.update(function_env or {})
+    env.update(overrides or {})
+    env.update(system_environment(function_name))
+    return env
+
+
+def redact_environment(env: Dict[str, str]) -> Dict[str, str]:
+    """秘密情報らしきキーの値を伏せたコピーを返す（ログ出力用）"""
+    return {k: REDACTED if SECRET_KEY_PATTERN.search(k) else v for k, v in env.items()}
@@ -0,0 +1,113 @@
+"""
+Eviction Cost Model
+
+容量不足時に停止するアイドルコンテナを、再起動コストと解放できるメモリから選びます。
+単純な LRU ではなく、コールドスタートが速くイメージが小さい (再起動が安い) 関数や
+メモリを多く使っている関数から先に停止します。
+
+    restart_cost = COLD_START_WEIGHT * コールドスタート秒数 + IMAGE_WEIGHT * イメージ GB
+    score = restart_cost / (メモリ GB * (1 + アイドル分数))
+
+score が小さいものから停止対象になります。
+"""
+
+from dataclasses import dataclass, field
+from typing import Dict, List
+
+# 計測値がない関数のコールドスタート秒数
+DEFAULT_COLD_START_SECONDS = 1.0
+# コールドスタート秒数の指数移動平均の重み
+COLD_START_SMOOTHING = 0.3
+
+_GB = 1024 * 1024 * 1024
+# 0 除算を避けるためのメモリ下限 (1MB)
+_MIN_MEMORY_GB = 1 / 1024
+
+
+@dataclass
+class FunctionProfile:
+    """関数ごとの再起動コストの計測値"""
+
+    cold_start_seconds: float = DEFAULT_COLD_START_SECONDS
+    cold_starts: int = 0
+    image_bytes: int = 0
+
+
+@dataclass
+class EvictionCandidate:
+    """停止候補のアイドルコンテナ"""
+
+    container_name: str
+    function_name: str
+    idle_seconds: float
+    memory_bytes: int
+    profile: FunctionProfile = field(default_factory=FunctionProfile)
+    score: float = 0.0
+
+    def to_dict(self) -> Dict:
+        return {
+            "name": self.container_name,
+            "function": self.function_name,
+            "idle_seconds": int(self.idle_seconds),
+            "memory_bytes": self.memory_bytes,
+            "cold_start_seconds": round(self.profile.cold_start_seconds, 3),
+            "image_bytes": self.profile.image_bytes,
+            "score": round(self.score, 6),
+        }
+
+
+class ProfileStore:
+    """
+    関数ごとのコールドスタート時間とイメージサイズを保持する
+    """
+
+    def __init__(self):
+        self.profiles: Dict[str, FunctionProfile] = {}
+
+    def get(self, function_name: str) -> FunctionProfile:
+        return self.profiles.get(function_name) or FunctionProfile()
+
+    def record_cold_start(self, function_name: str, seconds: float) -> None:
+        """コールドスタート時間を記録 (初回はそのまま、以降は指数移動平均)"""
+        profile = self.profiles.setdefault(function_name, FunctionProfile())
+        if profile.cold_starts == 0:
+            profile.cold_start_seconds = seconds
+        else:
+            profile.cold_start_seconds += COLD_START_SMOOTHING * (
+                seconds - profile.cold_start_seconds
+            )
+        profile.cold_starts += 1
+
+    def has_image_size(self, function_name: str) -> bool:
+        profile = self.profiles.get(function_name)
+        return profile is not None and profile.image_bytes > 0
+
+    def record_image_size(self, function_name: str, size_bytes: int) -> None:
+        self.profiles.setdefault(function_name, FunctionProfile()).image_bytes = size_bytes
+
+
+def eviction_score(
+    candidate: EvictionCandidate,
+    cold_start_weight: float = 1.0,
+    image_weight: float = 0.5,
+) -> float:
+    """停止の優先度 (小さいほど先に停止する)"""
+    restart_cost = (
+        cold_start_weight * candidate.profile.cold_start_seconds
+        + image_weight * candidate.profile.image_bytes / _GB
+    )
+    memory_gb = max(candidate.memory_bytes / _GB, _MIN_MEMORY_GB)
+    recency = 1 + candidate.idle_seconds / 60
+    return restart_cost / (memory_gb * recency)
+
+
+def rank_candidates(
+    candidates: List[EvictionCandidate],
+    cold_start_weight: float = 1.0,
+    image_weight: float = 0.5,
+) -> List[EvictionCandidate]:
+    """スコアを計算し、停止する順に並べる (同点はアイドル時間が長い順)"""
+    for candidate in candidates:
+        candidate.score = eviction_score(candidate, cold_start_weight, image_weight)
+    return sorted(candidates, key=lambda c: (c.score, -c.idle_seconds))
+
@@ -0,0 +1,139 @@
+"""
+Fault Injection (カオステスト用)
+
+DockerAdaptor をラップし、設定したルールに従って操作を遅延・失敗・部分完了させます。
+ロールバック処理や停止済み・孤立コンテナの回収をテストやステージング環境で検証するために使用します。
+
+- delay: delay_seconds 待ってから操作を実行
+- fail: 操作を実行せずに失敗
+- partial: 操作を実行した後に失敗 (例: コンテナ作成は成功したがネットワーク設定で失敗)
+
+発生判定は操作ごとに seed から初期化した乱数を使うため、同じ seed・同じ操作順なら
+同じ箇所で障害が発生します。
+"""
+
+import asyncio
+import logging
+import random
+from dataclasses import dataclass, fields
+from typing import Any, Dict, List, Optional
+
+import docker.errors
+
+logger = logging.getLogger("orchestrator.faults")
+
+FAULT_ACTIONS = ("delay", "fail", "partial")
+
+
+class InjectedFaultError(docker.errors.APIError):
+    """
+    注入された障害
+
+    実際の Docker API エラーと同じ経路で処理されるよう APIError を継承します。
+    """
+
+    def __init__(self, operation: str, action: str):
+        self.operation = operation
+        self.action = action
+        super().__init__(f"Injected fault ({action}) in {operation}")
+
+
+@dataclass
+class FaultRule:
+    """障害注入のルール"""
+
+    # 対象の DockerAdaptor メソッド名 (例: run_container)
+    operation: str
+    action: str = "fail"
+    # 呼び出しごとの発生確率
+    probability: float = 1.0
+    delay_seconds: float = 0.0
+    # 発生回数の上限 (None は無制限)
+    max_count: Optional[int] = None
+    injected: int = 0
+
+
+# 設定値として指定できるキー (injected は実行時の集計値のため除外)
+FAULT_RULE_KEYS = tuple(f.name for f in fields(FaultRule) if f.name != "injected")
+
+
+def parse_rules(raw_rules: List[Dict[str, Any]]) -> List[FaultRule]:
+    """設定値 (FAULT_INJECTION_RULES) からルールを生成"""
+    rules = []
+    for index, raw in enumerate(raw_rules):
+        if not isinstance(raw, dict):
+            raise ValueError(f"Fault rule #{index} must be an object, got {type(raw).__name__}")
+        unknown = sorted(set(raw) - set(FAULT_RULE_KEYS))
+        if unknown:
+            raise ValueError(
+                f"Unknown key(s) {', '.join(repr(k) for k in unknown)} in fault rule #{index} "
+                f"(expected {', '.join(FAULT_RULE_KEYS)})"
+            )
+        if "operation" not in raw:
+            raise ValueError(f"Fault rule #{index} is missing required key 'operation'")
+        rule = FaultRule(**raw)
+        if rule.action not in FAULT_ACTIONS:
+            raise ValueError(
+                f"Unknown fault action '{rule.action}' for {rule.operation} "
+                f"(expected one of {', '.join(FAULT_ACTIONS)})"
+            )
+        rules.append(rule)
+    return rules
+
+
+class FaultInjectingAdaptor:
+    """
+    障害注入付きの DockerAdaptor ラッパー
+
+    ルールのない操作・属性はそのまま元のアダプターに委譲します。
+    """
+
+    def __init__(self, adaptor: Any, rules: List[FaultRule], seed: int = 0):
+        self._adaptor = adaptor
+        self._rules: Dict[str, List[FaultRule]] = {}
+        for rule in rules:
+            self._rules.setdefault(rule.operation, []).append(rule)
+        self._random = {
+            operation: random.Random(f"{seed}:{operation}") for operation in self._rules
+        }
+
+    def __getattr__(self, name: str) -> Any:
+        attr = getattr(self._adaptor, name)
+        if name not in self._rules or not asyncio.iscoroutinefunction(attr):
+            return attr
+
+        async def wrapper(*args, **kwargs):
+            return await self._call(name, attr, *args, **kwargs)
+
+        return wrapper
+
+    def _choose(self, operation: str) -> Optional[FaultRule]:
+        rng = self._random[operation]
+        for rule in self._rules[operation]:
+            if rule.max_count is not None and rule.injected >= rule.max_count:
+                continue
+            if rng.random() < rule.probability:
+                rule.injected += 1
+                return rule
+        return None
+
+    async def _call(self, operation: str, func, *args, **kwargs) -> Any:
+        rule = self._choose(operation)
+        if rule is None:
+            return await func(*args, **kwargs)
+
+        logger.warning(f"Injecting fault into {operation}: {rule.action}")
+        if rule.action == "delay":
+            await asyncio.sleep(rule.delay_seconds)
+            return await func(*args, **kwargs)
+        if rule.action == "partial":
+            await func(*args, **kwargs)
+        raise InjectedFaultError(operation, rule.action)
+
+    def snapshot(self) -> List[Dict]:
+        """ルールごとの発生回数"""
+        return [
+            {"operation": rule.operation, "action": rule.action, "injected": rule.injected}
+            for rules in self._rules.values()
+            for rule in rules
+        ]
@@ -0,0 +1,121 @@
+"""
+Hot Code Reload (開発用)
+
+DEV_CODE_ROOT 配下の関数ディレクトリをコンテナのコード配置先にバインドし、
+inotify (watchdog) で変更を検知して該当関数のコンテナを再起動します。
+イメージの再ビルドや pull を行わずにコードの変更を反映できます。
+
+関数名とディレクトリは {root}/{関数名}、または接頭辞 "lambda-" を除いた
+{root}/{ディレクトリ名} で対応付けます (例: lambda-echo -> functions/echo)。
+"""
+
+import asyncio
+import logging
+import os
+from typing import Awaitable, Callable, Dict, Optional, Set
+
+from watchdog.events import FileSystemEvent, FileSystemEventHandler
+from watchdog.observers import Observer
+
+logger = logging.getLogger("orchestrator.hot_reload")
+
+FUNCTION_NAME_PREFIX = "lambda-"
+
+# 変更として扱わないパス (バイトコードキャッシュなど)
+_IGNORED_PARTS = ("__pycache__", ".pytest_cache", ".git")
+_IGNORED_SUFFIXES = (".pyc", ".pyo", ".swp", "~")
+
+
+def code_directory_name(function_name: str, root: str) -> Optional[str]:
+    """関数のコードディレクトリ名 (root 直下に存在しない場合は None)"""
+    candidates = [function_name]
+    if function_name.startswith(FUNCTION_NAME_PREFIX):
+        candidates.append(function_name[len(FUNCTION_NAME_PREFIX) :])
+    for name in candidates:
+        if os.path.isdir(os.path.join(root, name)):
+            return name
+    return None
+
+
+def changed_directory_name(path: str, root: str) -> Optional[str]:
+    """変更されたパスが属する root 直下のディレクトリ名 (対象外のパスは None)"""
+    relative = os.path.relpath(path, root)
+    parts = relative.split(os.sep)
+    if len(parts) < 2 or parts[0] in (os.curdir, os.pardir):
+        return None
+    if any(part in _IGNORED_PARTS for part in parts) or path.endswith(_IGNORED_SUFFIXES):
+        return None
+    return parts[0]
+
+
+class CodeChangeHandler(FileSystemEventHandler):
+    """
+    ファイル変更イベントをディレクトリ単位にまとめ、イベントループ上でコールバックする
+
+    エディタの保存などで連続するイベントは debounce 秒間まとめて 1 回にします。
+    """
+
+    def __init__(
+        self,
+        root: str,
+        loop: asyncio.AbstractEventLoop,
+        callback: Callable[[str], Awaitable[None]],
+        debounce: float = 0.5,
+    ):
+        self.root = root
+        self.loop = loop
+        self.callback = callback
+        self.debounce = debounce
+        self._pending: Dict[str, asyncio.TimerHandle] = {}
+        # 実行中のコールバック (GC 対策で参照を保持)
+        self._tasks: Set[asyncio.Task] = set()
+
+    def on_any_event(self, event: FileSystemEvent) -> None:
+        # watchdog のスレッドから呼ばれる
+        if event.is_directory or event.event_type in ("opened", "closed_no_write"):
+            return
+        directory = changed_directory_name(os.fsdecode(event.src_path), self.root)
+        if directory is not None:
+            self.loop.call_soon_threadsafe(self._schedule, directory)
+
+    def _schedule(self, directory: str) -> None:
+        handle = self._pending.pop(directory, None)
+        if handle is not None:
+            handle.cancel()
+        self._pending[directory] = self.loop.call_later(self.debounce, self._fire, directory)
+
+    def _fire(self, directory: str) -> None:
+        self._pending.pop(directory, None)
+        task = self.loop.create_task(self.callback(directory))
+        self._tasks.add(task)
+        task.add_done_callback(self._tasks.discard)
+
+
+class CodeWatcher:
+    """DEV_CODE_ROOT を監視するオブザーバーのライフサイクル管理"""
+
+    def __init__(
+        self,
+        root: str,
+        callback: Callable[[str], Awaitable[None]],
+        debounce: float = 0.5,
+    ):
+        self.root = root
+        self.callback = callback
+        self.debounce = debounce
+        self._observer: Optional[Observer] = None
+
+    def start(self) -> None:
+        handler = CodeChangeHandler(
+            self.root, asyncio.get_running_loop(), self.callback, self.debounce
+        )
+        self._observer = Observer()
+        self._observer.schedule(handler, self.root, recursive=True)
+        self._observer.start()
+        logger.info(f"Watching {self.root} for code changes")
+
+    def stop(self) -> None:
+        if self._observer is not None:
+            self._observer.stop()
+            self._observer.join(timeout=5)
+            self._observer = None
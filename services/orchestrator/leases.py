@@ -0,0 +1,110 @@
+"""
+Worker Leases
+
+ensure で起動したコンテナに有効期限付きのリースを発行します。
+呼び出し元はリースを更新し続ける限りコンテナを保持でき、更新が途絶えたコンテナは
+アイドルタイムアウトを待たずに回収されます (リース中のコンテナはアイドル停止の対象外)。
+"""
+
+import time
+import uuid
+from dataclasses import asdict, dataclass
+from typing import Dict, List, Optional
+
+
+@dataclass
+class Lease:
+    """コンテナに対するリース"""
+
+    lease_id: str
+    container_name: str
+    function_name: str
+    ttl_seconds: int
+    expires_at: float
+
+    def to_dict(self) -> Dict:
+        return asdict(self)
+
+
+class LeaseNotFoundError(Exception):
+    """存在しない (期限切れで回収済みを含む) リースに対する操作の例外"""
+
+    def __init__(self, lease_id: str):
+        self.lease_id = lease_id
+        super().__init__(f"Lease not found: {lease_id}")
+
+
+class LeaseTable:
+    """
+    コンテナごとに 1 つのリースを保持する
+
+    同じコンテナに対して再度発行した場合は、既存のリースの期限を延長して返します。
+    既存のリースが期限切れ (回収前) の場合は、それを破棄して新しいリースを発行します。
+    """
+
+    def __init__(self):
+        self._leases: Dict[str, Lease] = {}
+        # container_name -> lease_id
+        self._by_container: Dict[str, str] = {}
+
+    def grant(
+        self,
+        container_name: str,
+        function_name: str,
+        ttl_seconds: int,
+        now: Optional[float] = None,
+    ) -> Lease:
+        now = time.time() if now is None else now
+        lease_id = self._by_container.get(container_name)
+        if lease_id is not None:
+            if self._leases[lease_id].expires_at > now:
+                return self.renew(lease_id, ttl_seconds, now)
+            self.release(lease_id)
+
+        lease = Lease(
+            lease_id=uuid.uuid4().hex,
+            container_name=container_name,
+            function_name=function_name,
+            ttl_seconds=ttl_seconds,
+            expires_at=now + ttl_seconds,
+        )
+        self._leases[lease.lease_id] = lease
+        self._by_container[container_name] = lease.lease_id
+        return lease
+
+    def renew(
+        self, lease_id: str, ttl_seconds: Optional[int] = None, now: Optional[float] = None
+    ) -> Lease:
+        """リースの期限を延長 (ttl_seconds 未指定時は発行時の TTL)"""
+        now = time.time() if now is None else now
+        lease = self._leases.get(lease_id)
+        if lease is None or lease.expires_at <= now:
+            raise LeaseNotFoundError(lease_id)
+        if ttl_seconds:
+            lease.ttl_seconds = ttl_seconds
+        lease.expires_at = now + lease.ttl_seconds
+        return lease
+
+    def release(self, lease_id: str) -> Optional[Lease]:
+        lease = self._leases.pop(lease_id, None)
+        if lease is not None:
+            self._by_container.pop(lease.container_name, None)
+        return lease
+
+    def release_container(self, container_name: str) -> None:
+        lease_id = self._by_container.get(container_name)
+        if lease_id is not None:
+            self.release(lease_id)
+
+    def is_leased(self, container_name: str, now: Optional[float] = None) -> bool:
+        """有効なリースがあるか"""
+        now = time.time() if now is None else now
+        lease_id = self._by_container.get(container_name)
+        return lease_id is not None and self._leases[lease_id].expires_at > now
+
+    def expired(self, now: Optional[float] = None) -> List[Lease]:
+        now = time.time() if now is None else now
+        return [lease for lease in self._leases.values() if lease.expires_at <= now]
+
+    def snapshot(self) -> List[Dict]:
+        return [lease.to_dict() for lease in self._leases.values()]
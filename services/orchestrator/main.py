@@ -1,9 +1,21 @@
-from fastapi import FastAPI, HTTPException, Request
+from fastapi import FastAPI, HTTPException, Query, Request
+from fastapi.responses import StreamingResponse
+from starlette.concurrency import run_in_threadpool
 import logging
+import json
 from contextlib import asynccontextmanager
 import asyncio
+import os
+import tempfile
+from typing import Optional
 
 from .service import ContainerOrchestrator
+from .docker_adaptor import ImagePlatformError
+from .crash_loop import FunctionQuarantinedError
+from .admission import AdmissionRejectedError
+from .volumes import VolumeQuotaExceededError
+from .leases import LeaseNotFoundError
+from .hot_reload import CodeWatcher
 import docker.errors
 from services.common.core.request_context import (
     set_trace_id,
@@ -16,7 +28,11 @@ from services.common.models.internal import (
     ContainerInfoResponse,
     ContainerProvisionRequest,
     ContainerProvisionResponse,
+    FunctionSyncRequest,
     HeartbeatRequest,
+    ImageLoadResponse,
+    LeaseRenewRequest,
+    LeaseResponse,
 )
 
 # Logger setup
@@ -47,11 +63,48 @@ async def lifespan(app: FastAPI):
         id="idle_cleanup",
         args=[config.IDLE_TIMEOUT_MINUTES * 60],
     )
+    scheduler.add_job(
+        orchestrator.collect_memory_stats,
+        "interval",
+        seconds=config.MEMORY_STATS_INTERVAL_SECONDS,
+        id="memory_stats",
+    )
+    scheduler.add_job(
+        orchestrator.collect_volume_usage,
+        "interval",
+        seconds=config.VOLUME_USAGE_INTERVAL_SECONDS,
+        id="volume_usage",
+    )
+    scheduler.add_job(
+        orchestrator.reclaim_expired_leases,
+        "interval",
+        seconds=config.LEASE_RECLAIM_INTERVAL_SECONDS,
+        id="lease_reclaim",
+    )
+    if config.EXTERNAL_ADOPTION_ENABLED:
+        scheduler.add_job(
+            orchestrator.adopt_external_containers,
+            "interval",
+            seconds=config.EXTERNAL_ADOPTION_INTERVAL_SECONDS,
+            id="external_adoption",
+        )
     scheduler.start()
     logger.info(f"Idle cleanup scheduler started (timeout: {config.IDLE_TIMEOUT_MINUTES}m)")
 
+    # OOM / 終了イベントの購読
+    event_watcher = asyncio.create_task(orchestrator.watch_container_events())
+
+    # 開発用: コード変更の監視とコンテナ再起動
+    code_watcher = None
+    if config.DEV_CODE_ROOT:
+        code_watcher = CodeWatcher(config.DEV_CODE_ROOT, orchestrator.reload_function_code)
+        code_watcher.start()
+
     yield
     # Shutdown logic
+    if code_watcher is not None:
+        code_watcher.stop()
+    event_watcher.cancel()
     await orchestrator.shutdown()
     scheduler.shutdown()
 
@@ -108,8 +161,37 @@ async def ensure_container(req: ContainerEnsureRequest, request: Request):
     """
 
     try:
-        host = await orchestrator.ensure_container_running(req.function_name, req.image, req.env)
+        host = await orchestrator.ensure_container_running(
+            req.function_name,
+            req.image,
+            req.env,
+            req.platform,
+            env_overrides=req.env_overrides,
+            volume=req.volume,
+            security=req.security,
+        )
+        if req.lease_ttl_seconds:
+            lease = orchestrator.grant_lease(host, req.function_name, req.lease_ttl_seconds)
+            return ContainerInfoResponse(
+                host=host,
+                port=config.LAMBDA_PORT,
+                lease_id=lease.lease_id,
+                lease_expires_at=lease.expires_at,
+            )
         return ContainerInfoResponse(host=host, port=config.LAMBDA_PORT)
+    except FunctionQuarantinedError as e:
+        logger.warning(str(e))
+        raise HTTPException(status_code=503, detail=str(e))
+    except AdmissionRejectedError as e:
+        raise HTTPException(
+            status_code=429, detail={"message": str(e), "shortfall": e.shortfall}
+        )
+    except VolumeQuotaExceededError as e:
+        logger.warning(str(e))
+        raise HTTPException(status_code=507, detail=str(e))
+    except ImagePlatformError as e:
+        logger.error(f"Image platform mismatch: {e}")
+        raise HTTPException(status_code=400, detail=str(e))
     except docker.errors.ImageNotFound as e:
         logger.error(f"Image not found: {e.explanation}")
         raise HTTPException(status_code=404, detail=f"Lambda image not found: {e.explanation}")
@@ -146,8 +228,25 @@ async def provision_containers(req: ContainerProvisionRequest):
             count=req.count,
             image=req.image,
             env=req.env,
+            platform=req.platform,
+            env_overrides=req.env_overrides,
+            volume=req.volume,
+            security=req.security,
         )
         return ContainerProvisionResponse(workers=workers)
+    except FunctionQuarantinedError as e:
+        logger.warning(str(e))
+        raise HTTPException(status_code=503, detail=str(e))
+    except AdmissionRejectedError as e:
+        raise HTTPException(
+            status_code=429, detail={"message": str(e), "shortfall": e.shortfall}
+        )
+    except VolumeQuotaExceededError as e:
+        logger.warning(str(e))
+        raise HTTPException(status_code=507, detail=str(e))
+    except ImagePlatformError as e:
+        logger.error(f"Image platform mismatch: {e}")
+        raise HTTPException(status_code=400, detail=str(e))
     except docker.errors.ImageNotFound as e:
         logger.error(f"Image not found: {e.explanation}")
         raise HTTPException(status_code=404, detail=f"Lambda image not found: {e.explanation}")
@@ -159,6 +258,30 @@ async def provision_containers(req: ContainerProvisionRequest):
         raise HTTPException(status_code=500, detail="Internal server error provisioning containers")
 
 
+@app.get("/leases")
+async def list_leases():
+    """発行中のリース一覧"""
+    return {"leases": orchestrator.leases.snapshot()}
+
+
+@app.post("/leases/{lease_id}/renew", response_model=LeaseResponse)
+async def renew_lease(lease_id: str, req: Optional[LeaseRenewRequest] = None):
+    """リースの期限を延長（期限切れ・回収済みの場合は 404）"""
+    try:
+        lease = orchestrator.renew_lease(lease_id, req.ttl_seconds if req else None)
+    except LeaseNotFoundError as e:
+        raise HTTPException(status_code=404, detail=str(e))
+    return LeaseResponse(**lease.to_dict())
+
+
+@app.delete("/leases/{lease_id}")
+async def release_lease(lease_id: str):
+    """リースを返却（コンテナはアイドル停止の対象に戻る）"""
+    if orchestrator.release_lease(lease_id) is None:
+        raise HTTPException(status_code=404, detail=f"Lease not found: {lease_id}")
+    return {"status": "released"}
+
+
 @app.post("/containers/heartbeat")
 async def heartbeat(req: HeartbeatRequest):
     """Gateway からの Heartbeat 受信"""
@@ -166,9 +289,25 @@ async def heartbeat(req: HeartbeatRequest):
     return {"status": "ok"}
 
 
+@app.post("/containers/{container_id}/stop")
+async def stop_container(
+    container_id: str,
+    grace_period: Optional[int] = Query(None, ge=0, description="SIGKILL までの猶予期間(秒)"),
+):
+    """コンテナを停止 (SIGTERM -> 猶予期間 -> SIGKILL)。コンテナは削除しない"""
+    try:
+        await orchestrator.stop_container(container_id, grace_period=grace_period, remove=False)
+        return {"status": "stopped"}
+    except docker.errors.NotFound:
+        raise HTTPException(status_code=404, detail="Container not found")
+    except Exception as e:
+        logger.error(f"Error stopping container: {e}", exc_info=True)
+        raise HTTPException(status_code=500, detail=str(e))
+
+
 @app.delete("/containers/{container_id}")
 async def delete_container(container_id: str):
-    """コンテナを即時削除"""
+    """コンテナを停止 (SIGTERM -> 猶予期間 -> SIGKILL) して削除"""
     try:
         await orchestrator.stop_container(container_id)
         return {"status": "deleted"}
@@ -179,6 +318,91 @@ async def delete_container(container_id: str):
         raise HTTPException(status_code=500, detail=str(e))
 
 
+@app.get("/functions/quarantine")
+async def list_quarantined_functions():
+    """クラッシュループにより隔離中の関数一覧"""
+    return {"functions": orchestrator.get_quarantined_functions()}
+
+
+@app.delete("/functions/{function_name}/quarantine")
+async def clear_quarantine(function_name: str):
+    """関数の隔離を手動で解除"""
+    if not orchestrator.clear_quarantine(function_name):
+        raise HTTPException(status_code=404, detail=f"Function {function_name} is not quarantined")
+    return {"status": "cleared"}
+
+
+@app.post("/functions/sync")
+async def sync_functions(req: FunctionSyncRequest):
+    """
+    あるべき関数一覧との差分を適用し、進捗を NDJSON でストリーミング
+
+    1 行目に差分 (plan)、以降に操作ごとの進捗 (progress)、最後に集計 (complete) を返す。
+    dry_run の場合は plan のみを返す。
+    """
+    try:
+        plan = await orchestrator.plan_function_sync(req.functions, prune=req.prune)
+    except Exception as e:
+        logger.error(f"Error planning function sync: {e}", exc_info=True)
+        raise HTTPException(status_code=500, detail=str(e))
+
+    async def events():
+        yield json.dumps({"event": "plan", "dry_run": req.dry_run, "steps": plan}) + "\n"
+        if req.dry_run:
+            return
+        summary = {"done": 0, "failed": 0}
+        async for event in orchestrator.apply_function_sync(plan):
+            summary[event["status"]] += 1
+            yield json.dumps({"event": "progress", **event}) + "\n"
+        yield json.dumps({"event": "complete", **summary}) + "\n"
+
+    return StreamingResponse(events(), media_type="application/x-ndjson")
+
+
+@app.get("/functions/volumes")
+async def list_function_volumes():
+    """関数ごとの永続ボリューム使用量とクォータ"""
+    return {"functions": orchestrator.get_volume_stats()}
+
+
+@app.get("/containers/stats")
+async def container_stats():
+    """関数ごとの OOM 回数・ピークメモリ・推奨メモリを取得"""
+    return {"functions": orchestrator.get_resource_stats()}
+
+
+@app.get("/containers/idle")
+async def simulate_idle_cleanup(
+    timeout_minutes: Optional[float] = Query(
+        None, gt=0, description="評価するアイドルタイムアウト(分)。省略時は IDLE_TIMEOUT_MINUTES"
+    ),
+):
+    """アイドルクリーンアップのドライラン（停止対象を返すのみで停止は行わない）"""
+    if timeout_minutes is None:
+        timeout_minutes = config.IDLE_TIMEOUT_MINUTES
+    timeout_seconds = int(timeout_minutes * 60)
+    try:
+        containers = await orchestrator.simulate_idle_cleanup(timeout_seconds)
+        return {"dry_run": True, "timeout_seconds": timeout_seconds, "containers": containers}
+    except Exception as e:
+        logger.error(f"Error simulating idle cleanup: {e}", exc_info=True)
+        raise HTTPException(status_code=500, detail=str(e))
+
+
+@app.get("/containers/eviction")
+async def list_eviction_candidates():
+    """容量不足時の停止候補を停止する順に返す（停止は行わない）"""
+    try:
+        candidates = await orchestrator.get_eviction_candidates()
+        return {
+            "enabled": config.EVICTION_ENABLED,
+            "candidates": [c.to_dict() for c in candidates],
+        }
+    except Exception as e:
+        logger.error(f"Error listing eviction candidates: {e}", exc_info=True)
+        raise HTTPException(status_code=500, detail=str(e))
+
+
 @app.get("/containers/sync")
 async def list_containers():
     """全コンテナ一覧を取得 (Adoption用)"""
@@ -188,3 +412,68 @@ async def list_containers():
     except Exception as e:
         logger.error(f"Error listing containers: {e}", exc_info=True)
         raise HTTPException(status_code=500, detail=str(e))
+
+
+# =============================================================================
+# Image Management API (Offline / Air-gapped)
+# =============================================================================
+
+
+def _resolve_archive_path(path: str) -> str:
+    """IMAGE_ARCHIVE_DIR 配下のパスに解決（ディレクトリ外への参照は拒否）"""
+    base_dir = os.path.realpath(config.IMAGE_ARCHIVE_DIR)
+    archive_path = os.path.realpath(os.path.join(base_dir, path))
+    if os.path.commonpath([base_dir, archive_path]) != base_dir:
+        raise HTTPException(
+            status_code=400, detail=f"Archive path must be under {config.IMAGE_ARCHIVE_DIR}"
+        )
+    return archive_path
+
+
+@app.post("/images/load", response_model=ImageLoadResponse)
+async def load_image_archive(request: Request, path: Optional[str] = None):
+    """
+    イメージアーカイブ (docker save / OCI tar) を読み込み
+
+    - path 指定あり: IMAGE_ARCHIVE_DIR 配下のローカルファイルを読み込む
+    - path 指定なし: リクエストボディの tar をストリーミング受信して読み込む
+    """
+    try:
+        if path:
+            archive_path = _resolve_archive_path(path)
+            if not os.path.isfile(archive_path):
+                raise HTTPException(status_code=404, detail=f"Archive not found: {path}")
+            images = await orchestrator.load_image_archive(archive_path)
+        else:
+            # メモリに載せずに一時ファイルへ書き出す (ファイル I/O はスレッドプールで実行)
+            tmp = await run_in_threadpool(tempfile.NamedTemporaryFile, suffix=".tar")
+            max_bytes = config.IMAGE_ARCHIVE_MAX_BYTES
+            size = 0
+            try:
+                async for chunk in request.stream():
+                    # 上限を超えた時点で受信を打ち切り、DinD のディスク枯渇を防ぐ
+                    size += len(chunk)
+                    if max_bytes and size > max_bytes:
+                        raise HTTPException(
+                            status_code=413,
+                            detail=f"Image archive exceeds {max_bytes} bytes",
+                        )
+                    await run_in_threadpool(tmp.write, chunk)
+                await run_in_threadpool(tmp.flush)
+                if tmp.tell() == 0:
+                    raise HTTPException(status_code=400, detail="Empty image archive")
+                images = await orchestrator.load_image_archive(tmp.name)
+            finally:
+                await run_in_threadpool(tmp.close)
+        return ImageLoadResponse(images=images)
+    except HTTPException:
+        raise
+    except docker.errors.ImageLoadError as e:
+        logger.error(f"Invalid image archive: {e}")
+        raise HTTPException(status_code=400, detail=f"Invalid image archive: {e}")
+    except docker.errors.APIError as e:
+        logger.error(f"Docker API error: {e.explanation}")
+        raise HTTPException(status_code=400, detail=f"Docker API error: {e.explanation}")
+    except Exception as e:
+        logger.error(f"Error loading image archive: {e}", exc_info=True)
+        raise HTTPException(status_code=500, detail="Internal server error loading image archive")
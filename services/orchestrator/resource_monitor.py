@@ -0,0 +1,131 @@
+"""
+Resource Monitor
+
+関数ごとの OOM Kill 回数とピークメモリ使用量を記録し、
+推奨メモリサイズを算出します。
+"""
+
+import logging
+import math
+import time
+from dataclasses import dataclass
+from typing import Any, Dict, Optional
+
+logger = logging.getLogger("orchestrator.resource_monitor")
+
+# コンテナ状態: OOM Kill により停止したことを示す
+OOM_KILLED = "OOM_KILLED"
+
+# 推奨メモリの丸め単位 (64MB)
+MEMORY_ROUNDING_BYTES = 64 * 1024 * 1024
+# 推奨メモリの下限 (128MB、Lambda の最小値)
+MIN_SUGGESTED_MEMORY_BYTES = 128 * 1024 * 1024
+
+
+@dataclass
+class FunctionResourceStats:
+    """関数単位のリソース統計"""
+
+    oom_kills: int = 0
+    last_oom_at: float = 0.0
+    peak_memory_bytes: int = 0
+    memory_limit_bytes: int = 0
+
+
+def extract_memory_usage(stats: Dict[str, Any]) -> tuple[int, int]:
+    """
+    Docker stats のレスポンスから (使用量, 上限) を取り出す
+
+    cgroup v1 では max_usage、v2 では usage を使用量として扱います。
+    """
+    memory_stats = stats.get("memory_stats") or {}
+    usage = memory_stats.get("max_usage") or memory_stats.get("usage") or 0
+    limit = memory_stats.get("limit") or 0
+    return int(usage), int(limit)
+
+
+def cgroup_memory_limit(attrs: Dict[str, Any]) -> int:
+    """
+    コンテナに設定されたメモリ上限 (HostConfig.Memory) を取り出す (未設定なら 0)
+
+    上限のないコンテナでは Docker stats の limit がホストのメモリ量になるため、
+    実際の上限の判定にはこちらを使用します。
+    """
+    return int((attrs.get("HostConfig") or {}).get("Memory") or 0)
+
+
+class ResourceMonitor:
+    """
+    OOM イベントとメモリ使用量を関数単位で集計する
+    """
+
+    def __init__(self, headroom: float = 1.5):
+        self.headroom = headroom
+        self.functions: Dict[str, FunctionResourceStats] = {}
+        # コンテナ名 -> 最終状態 (OOM_KILLED など)
+        self.container_states: Dict[str, str] = {}
+
+    def _get(self, function_name: str) -> FunctionResourceStats:
+        if function_name not in self.functions:
+            self.functions[function_name] = FunctionResourceStats()
+        return self.functions[function_name]
+
+    def record_oom(self, function_name: str, container_name: str) -> None:
+        """OOM Kill を記録"""
+        stats = self._get(function_name)
+        stats.oom_kills += 1
+        stats.last_oom_at = time.time()
+        self.container_states[container_name] = OOM_KILLED
+        logger.warning(
+            f"Container {container_name} ({function_name}) was OOM killed "
+            f"(total: {stats.oom_kills})"
+        )
+
+    def record_memory(self, function_name: str, usage_bytes: int, limit_bytes: int = 0) -> None:
+        """
+        メモリ使用量のサンプルを記録（ピーク値のみ保持）
+
+        limit_bytes はコンテナに cgroup のメモリ上限が設定されている場合のみ指定します。
+        """
+        stats = self._get(function_name)
+        stats.peak_memory_bytes = max(stats.peak_memory_bytes, usage_bytes)
+        if limit_bytes:
+            stats.memory_limit_bytes = limit_bytes
+
+    def clear_container_state(self, container_name: str) -> None:
+        self.container_states.pop(container_name, None)
+
+    def get_container_state(self, container_name: str) -> Optional[str]:
+        return self.container_states.get(container_name)
+
+    def suggest_memory(self, function_name: str) -> Optional[int]:
+        """
+        推奨メモリサイズ (bytes) を算出
+
+        ピーク使用量に余裕率を掛けた値を基本とし、OOM が発生していてメモリ上限が
+        記録されている場合は、その上限に余裕率を掛けた値を下回らないようにします。
+        """
+        stats = self.functions.get(function_name)
+        if stats is None or (stats.peak_memory_bytes == 0 and stats.oom_kills == 0):
+            return None
+
+        suggested = stats.peak_memory_bytes * self.headroom
+        if stats.oom_kills and stats.memory_limit_bytes:
+            suggested = max(suggested, stats.memory_limit_bytes * self.headroom)
+
+        rounded = math.ceil(suggested / MEMORY_ROUNDING_BYTES) * MEMORY_ROUNDING_BYTES
+        return max(rounded, MIN_SUGGESTED_MEMORY_BYTES)
+
+    def snapshot(self) -> Dict[str, Dict[str, Any]]:
+        """統計情報を API レスポンス用の辞書に変換"""
+        result = {}
+        for name, stats in self.functions.items():
+            suggested = self.suggest_memory(name)
+            result[name] = {
+                "oom_kills": stats.oom_kills,
+                "last_oom_at": stats.last_oom_at,
+                "peak_memory_bytes": stats.peak_memory_bytes,
+                "memory_limit_bytes": stats.memory_limit_bytes,
+                "suggested_memory_mb": suggested // (1024 * 1024) if suggested else None,
+            }
+        return result
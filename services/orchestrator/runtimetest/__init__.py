@@ -0,0 +1,28 @@
+"""
+Runtime Conformance Test Harness
+
+実際の Docker (または Docker 互換) ソケットに対して Orchestrator を動かし、
+Ensure → Invoke → Pause → Resume → Destroy の一連の流れを検証する共通スイートです。
+DockerAdaptor 互換のランタイム実装は RuntimeConformance を継承してランタイムを差し替えるだけで、
+同じスイートで適合性を確認できます。
+
+ESB_RUNTIME_TEST=1 を設定した場合のみ実行されます (通常の単体テストではスキップ)。
+"""
+
+from .harness import (
+    RUNTIME_TEST_ENV,
+    RuntimeConformance,
+    RuntimeTestSettings,
+    invoke_function,
+    requires_runtime,
+    running_function,
+)
+
+__all__ = [
+    "RUNTIME_TEST_ENV",
+    "RuntimeConformance",
+    "RuntimeTestSettings",
+    "invoke_function",
+    "requires_runtime",
+    "running_function",
+]
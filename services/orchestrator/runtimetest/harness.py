@@ -0,0 +1,165 @@
+"""
+ランタイム適合性スイートの実装
+
+ContainerOrchestrator を実際のコンテナランタイムに接続し、関数コンテナのライフサイクル
+(Ensure → Invoke → Pause → Resume → Destroy) を検証します。
+"""
+
+import os
+import uuid
+from contextlib import asynccontextmanager, suppress
+from dataclasses import dataclass
+from typing import Any, AsyncIterator, Dict, Optional
+
+import docker.errors
+import httpx
+import pytest
+
+from ..config import config
+from ..docker_adaptor import DockerAdaptor
+from ..service import ContainerOrchestrator
+
+# スイートを有効化する環境変数 (未設定時はスキップ)
+RUNTIME_TEST_ENV = "ESB_RUNTIME_TEST"
+
+requires_runtime = pytest.mark.skipif(
+    os.environ.get(RUNTIME_TEST_ENV) != "1",
+    reason=f"Set {RUNTIME_TEST_ENV}=1 to run against a real container runtime",
+)
+
+
+@dataclass
+class RuntimeTestSettings:
+    """スイートの実行設定"""
+
+    # RIE を含む関数イメージ (例: esb build で生成した lambda-echo)
+    image: str = "lambda-echo:latest"
+    # 関数コンテナを接続するネットワーク (テスト実行側から IP で到達できること)
+    network: str = "bridge"
+    invoke_timeout: float = 30.0
+
+    @classmethod
+    def from_env(cls) -> "RuntimeTestSettings":
+        defaults = cls()
+        return cls(
+            image=os.environ.get("ESB_RUNTIME_TEST_IMAGE", defaults.image),
+            network=os.environ.get("ESB_RUNTIME_TEST_NETWORK", defaults.network),
+            invoke_timeout=float(
+                os.environ.get("ESB_RUNTIME_TEST_INVOKE_TIMEOUT", defaults.invoke_timeout)
+            ),
+        )
+
+
+async def container_address(runtime: Any, name: str, network: str) -> str:
+    """コンテナのネットワーク上の IP (取得できない場合はコンテナ名)"""
+    container = await runtime.get_container(name)
+    await runtime.reload_container(container)
+    networks = container.attrs.get("NetworkSettings", {}).get("Networks", {})
+    return networks.get(network, {}).get("IPAddress") or name
+
+
+async def invoke_function(
+    address: str, payload: Optional[Dict] = None, timeout: float = 30.0
+) -> httpx.Response:
+    """RIE の Invoke API を直接呼び出す"""
+    url = f"http://{address}:{config.LAMBDA_PORT}/2015-03-31/functions/function/invocations"
+    async with httpx.AsyncClient() as client:
+        return await client.post(url, json=payload or {}, timeout=timeout)
+
+
+@asynccontextmanager
+async def running_function(
+    orchestrator: ContainerOrchestrator, settings: RuntimeTestSettings
+) -> AsyncIterator[str]:
+    """
+    テスト用の関数コンテナを Ensure し、コンテナ名を返す
+
+    ブロックを抜けると (失敗時も) コンテナを停止・削除します。
+    """
+    name = f"lambda-runtimetest-{uuid.uuid4().hex[:8]}"
+    try:
+        await orchestrator.ensure_container_running(name, image=settings.image)
+        yield name
+    finally:
+        # Ensure 自体が失敗した場合はコンテナが存在せず、本来の失敗を隠さないよう無視する
+        with suppress(docker.errors.NotFound):
+            await orchestrator.stop_container(name, grace_period=0)
+
+
+@requires_runtime
+class RuntimeConformance:
+    """
+    ランタイム適合性スイート
+
+    継承したクラス (名前は Test で始める) が pytest に収集されます。
+    DockerAdaptor 以外のランタイム実装を検証する場合は create_runtime をオーバーライドします。
+    """
+
+    def create_runtime(self) -> Any:
+        return DockerAdaptor()
+
+    @pytest.fixture
+    def settings(self) -> RuntimeTestSettings:
+        return RuntimeTestSettings.from_env()
+
+    @pytest.fixture
+    def orchestrator(self, settings: RuntimeTestSettings):
+        orchestrator = ContainerOrchestrator(network=settings.network)
+        default_runtime = orchestrator.docker
+        orchestrator.docker = self.create_runtime()
+        if orchestrator.docker is not default_runtime:
+            default_runtime.shutdown()
+        yield orchestrator
+        orchestrator.docker.shutdown()
+
+    @pytest.mark.asyncio
+    async def test_ensure_starts_container(self, orchestrator, settings):
+        async with running_function(orchestrator, settings) as name:
+            container = await orchestrator.docker.get_container(name)
+
+            assert container.status == "running"
+            assert name in orchestrator.last_accessed
+
+    @pytest.mark.asyncio
+    async def test_ensure_reuses_running_container(self, orchestrator, settings):
+        async with running_function(orchestrator, settings) as name:
+            first = await orchestrator.docker.get_container(name)
+            await orchestrator.ensure_container_running(name, image=settings.image)
+            second = await orchestrator.docker.get_container(name)
+
+            assert first.id == second.id
+
+    @pytest.mark.asyncio
+    async def test_invoke(self, orchestrator, settings):
+        async with running_function(orchestrator, settings) as name:
+            address = await container_address(orchestrator.docker, name, settings.network)
+            response = await invoke_function(address, {"ping": True}, settings.invoke_timeout)
+
+            assert response.status_code == 200
+
+    @pytest.mark.asyncio
+    async def test_pause_and_resume(self, orchestrator, settings):
+        async with running_function(orchestrator, settings) as name:
+            container = await orchestrator.docker.get_container(name)
+
+            await orchestrator.docker.pause_container(container)
+            await orchestrator.docker.reload_container(container)
+            assert container.status == "paused"
+
+            await orchestrator.docker.unpause_container(container)
+            await orchestrator.docker.reload_container(container)
+            assert container.status == "running"
+
+            # 再開後も呼び出せること
+            address = await container_address(orchestrator.docker, name, settings.network)
+            response = await invoke_function(address, {"ping": True}, settings.invoke_timeout)
+            assert response.status_code == 200
+
+    @pytest.mark.asyncio
+    async def test_destroy_removes_container(self, orchestrator, settings):
+        async with running_function(orchestrator, settings) as name:
+            pass
+
+        with pytest.raises(docker.errors.NotFound):
+            await orchestrator.docker.get_container(name)
+        assert name not in orchestrator.last_accessed
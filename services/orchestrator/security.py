@@ -0,0 +1,44 @@
+"""
+コンテナのセキュリティ設定
+
+実行ユーザー・補助グループ・Linux capability を組み立てます。
+capability は常に全て破棄 (cap_drop=ALL) した上で、許可リストのみを付与します。
+"""
+
+from typing import Any, Dict, List, Optional
+
+from .config import config
+from services.common.models.internal import ContainerSecurity
+
+
+def _normalize_capability(cap: str) -> str:
+    """'cap_net_bind_service' / 'NET_BIND_SERVICE' を Docker の表記に揃える"""
+    cap = cap.strip().upper()
+    return cap[len("CAP_") :] if cap.startswith("CAP_") else cap
+
+
+def build_security_options(security: Optional[ContainerSecurity] = None) -> Dict[str, Any]:
+    """
+    run_container に渡すセキュリティ関連の引数を生成
+
+    関数ごとの設定が未指定の項目は Orchestrator のデフォルト
+    (CONTAINER_DEFAULT_USER / CONTAINER_DEFAULT_CAPABILITIES) を使用します。
+    """
+    security = security or ContainerSecurity()
+
+    capabilities: List[str] = (
+        security.capabilities
+        if security.capabilities is not None
+        else config.CONTAINER_DEFAULT_CAPABILITIES
+    )
+    options: Dict[str, Any] = {
+        "cap_drop": ["ALL"],
+        "cap_add": sorted({_normalize_capability(c) for c in capabilities}),
+    }
+
+    user = security.user or config.CONTAINER_DEFAULT_USER
+    if user:
+        options["user"] = user
+    if security.groups:
+        options["group_add"] = list(security.groups)
+    return options
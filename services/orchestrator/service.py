@@ -1,20 +1,52 @@
 import docker.errors
+import os
 import time
 import logging
 import asyncio
 import uuid
-from typing import Dict, List, Optional
+from typing import AsyncIterator, Dict, List, Optional, Set
 
 import httpx
 from .docker_adaptor import DockerAdaptor
 from .config import config
+from .resource_monitor import (
+    OOM_KILLED,
+    ResourceMonitor,
+    cgroup_memory_limit,
+    extract_memory_usage,
+)
+from .crash_loop import CrashLoopDetector, is_crash_exit_code
+from .clock import build_clock_options
+from .hot_reload import FUNCTION_NAME_PREFIX, code_directory_name
+from .environment import redact_environment, resolve_environment
+from .security import build_security_options
+from .adoption import follows_naming_convention
+from .admission import AdmissionController, AdmissionRejectedError, read_host_capacity
+from .faults import FaultInjectingAdaptor, parse_rules
+from .eviction import EvictionCandidate, ProfileStore, rank_candidates
+from .leases import Lease, LeaseTable
+from .volumes import (
+    VOLUME_FUNCTION_LABEL,
+    VOLUME_QUOTA_LABEL,
+    VolumeQuotaTracker,
+    volume_directory_name,
+    volume_driver_opts,
+    volume_name,
+)
 from services.common.core.http_client import HttpClientFactory
-from services.common.models.internal import WorkerInfo
+from services.common.models.internal import (
+    ContainerSecurity,
+    FunctionSpec,
+    FunctionVolume,
+    WorkerInfo,
+)
 
 logger = logging.getLogger("orchestrator.service")
 
 # ラベル用の短縮名（ブランド統一）
 PROJECT_LABEL = "esb"
+# 関数名を保持するラベル（コンテナ名から関数名を逆引きするため）
+FUNCTION_LABEL = "function_name"
 
 
 class ContainerOrchestrator:
@@ -24,11 +56,49 @@ class ContainerOrchestrator:
 
     def __init__(self, network: Optional[str] = None):
         self.docker = DockerAdaptor()
+        if config.FAULT_INJECTION_ENABLED:
+            self.docker = FaultInjectingAdaptor(
+                self.docker,
+                parse_rules(config.FAULT_INJECTION_RULES),
+                seed=config.FAULT_INJECTION_SEED,
+            )
+            logger.warning("Fault injection enabled for Docker operations")
         self.last_accessed: Dict[str, float] = {}
+        # 外部で起動され、採用ラベルにより管理下に取り込んだコンテナ名
+        self.adopted: Set[str] = set()
         # Per-container lock management
         self.locks: Dict[str, asyncio.Lock] = {}
         self._locks_lock = asyncio.Lock()
 
+        # OOM / メモリ使用量の集計
+        self.resource_monitor = ResourceMonitor(headroom=config.MEMORY_SUGGESTION_HEADROOM)
+        # クラッシュループ検出と隔離
+        self.crash_loop = CrashLoopDetector(
+            threshold=config.CRASH_LOOP_THRESHOLD,
+            window_seconds=config.CRASH_LOOP_WINDOW_SECONDS,
+            cooldown=config.QUARANTINE_COOLDOWN_SECONDS,
+        )
+        # 関数ごとの永続ボリュームのクォータ
+        self.volume_quota = VolumeQuotaTracker()
+        # ensure で発行したコンテナのリース
+        self.leases = LeaseTable()
+        # 関数ごとのコールドスタート時間・イメージサイズ（容量不足時の停止順に使用）
+        self.profiles = ProfileStore()
+        # ホスト容量に基づくアドミッション制御（無効時は None）
+        self.admission: Optional[AdmissionController] = None
+        if config.ADMISSION_CONTROL_ENABLED:
+            capacity = read_host_capacity()
+            self.admission = AdmissionController(
+                capacity,
+                memory_per_worker=config.WORKER_MEMORY_RESERVATION_MB * 1024 * 1024,
+                cpus_per_worker=config.WORKER_CPU_RESERVATION,
+                overcommit_factor=config.ADMISSION_OVERCOMMIT_FACTOR,
+            )
+            logger.info(
+                f"Admission control enabled (memory: {capacity.memory_bytes} bytes, "
+                f"cpus: {capacity.cpus}, overcommit: {config.ADMISSION_OVERCOMMIT_FACTOR})"
+            )
+
         # 共有 HTTP Client（startup() で初期化）
         self._http_factory = HttpClientFactory(config)
         self._http_client: Optional[httpx.AsyncClient] = None
@@ -43,15 +113,27 @@ class ContainerOrchestrator:
         logger.info("ContainerOrchestrator HTTP client initialized")
 
     async def ensure_container_running(
-        self, name: str, image: Optional[str] = None, env: Optional[Dict[str, str]] = None
+        self,
+        name: str,
+        image: Optional[str] = None,
+        env: Optional[Dict[str, str]] = None,
+        platform: Optional[str] = None,
+        env_overrides: Optional[Dict[str, str]] = None,
+        volume: Optional[FunctionVolume] = None,
+        security: Optional[ContainerSecurity] = None,
     ) -> str:
         """
         Ensures the container is running. Returns the hostname (container name) or IP.
+        Container env is composed by resolve_environment (defaults < env < env_overrides).
+        Raises FunctionQuarantinedError if the function is in a crash loop,
+        AdmissionRejectedError if starting it would exceed host capacity.
         """
-        self.last_accessed[name] = time.time()
+        self.crash_loop.check(name)
+        self.volume_quota.check(name)
 
         if image is None:
             image = f"{name}:latest"
+        platform = platform or config.CONTAINER_PLATFORM
 
         # Thread-safe acquisition of the per-container lock
         async with self._locks_lock:
@@ -61,6 +143,7 @@ class ContainerOrchestrator:
 
         # Use name-based lock to prevent race conditions (TOCTOU)
         async with lock:
+            cold_start_at: Optional[float] = None
             try:
                 container = await self.docker.get_container(name)
 
@@ -68,6 +151,17 @@ class ContainerOrchestrator:
                     pass  # Already running
 
                 elif container.status == "exited":
+                    if self._is_oom_killed(container):
+                        # イベント購読で記録済みでなければここで記録
+                        if self.resource_monitor.get_container_state(name) != OOM_KILLED:
+                            self.resource_monitor.record_oom(name, name)
+                        self.resource_monitor.clear_container_state(name)
+                        if config.OOM_RESTART_POLICY == "recreate":
+                            logger.info(f"Container {name} was OOM killed, recreating...")
+                            await self.docker.remove_container(container, force=True)
+                            raise docker.errors.NotFound(f"Removed {name}")
+
+                    await self._admit_or_evict(name, exclude=name)
                     logger.info(f"Warm-up: Restarting container {name}...")
                     # container.start() is blocking? DockerAdaptor doesn't have start() yet?
                     # Adaptor should have start helpers or we use generic run_in_executor
@@ -82,32 +176,36 @@ class ContainerOrchestrator:
             except docker.errors.NotFound:
                 # Cold start diagnostics
                 logger.info(f"Cold Start: Creating and starting container {name}...")
+                await self._admit_or_evict(name, exclude=name)
+                cold_start_at = time.time()
 
-                # Prevent log buffering
-                env = env or {}
-                env["PYTHONUNBUFFERED"] = "1"
-
-                # VictoriaLogs URL を Lambda に注入（直接ログ送信用）
-                import os
-
-                vl_host = os.environ.get("VICTORIALOGS_HOST", "victorialogs")
-                vl_port = os.environ.get("VICTORIALOGS_PORT", "9428")
-                env["VICTORIALOGS_URL"] = f"http://{vl_host}:{vl_port}/insert/jsonline"
-
-                # AWS Lambda 互換環境変数を設定（sitecustomize.py での動的ログ処理に使用）
-                env["AWS_LAMBDA_FUNCTION_NAME"] = name
+                container_env = resolve_environment(name, env, env_overrides)
+                logger.info(
+                    f"Environment variables for {name}: {redact_environment(container_env)}"
+                )
 
-                logger.info(f"Environment variables for {name}: {env}")
+                run_kwargs = {**build_security_options(security), **build_clock_options()}
+                if volume:
+                    volumes = await self._prepare_volume(name, volume)
+                    run_kwargs.setdefault("volumes", {}).update(volumes)
+                dev_code = self._dev_code_volume(name)
+                if dev_code:
+                    run_kwargs.setdefault("volumes", {}).update(dev_code)
+                if platform:
+                    # 指定プラットフォームのイメージを事前に確保（不一致なら ImagePlatformError）
+                    await self.docker.ensure_image(image, platform)
+                    run_kwargs["platform"] = platform
 
                 try:
                     container = await self.docker.run_container(
                         image,
                         name=name,
                         detach=True,
-                        environment=env or {},
+                        environment=container_env,
                         network=self.network,
                         restart_policy={"Name": "no"},
-                        labels={"created_by": PROJECT_LABEL},
+                        labels={"created_by": PROJECT_LABEL, FUNCTION_LABEL: name},
+                        **run_kwargs,
                     )
                 except docker.errors.APIError as e:
                     # 409 Conflict: コンテナが既に存在する（競合による作成）
@@ -120,6 +218,9 @@ class ContainerOrchestrator:
                     else:
                         raise e
 
+            # アクセス記録はコンテナの起動後に行う（アドミッションで拒否された要求を計上しない）
+            self.last_accessed[name] = time.time()
+
             # Reload container to get latest attributes (IP) and check readiness
             await self.docker.reload_container(container)
             try:
@@ -136,6 +237,8 @@ class ContainerOrchestrator:
 
             # Use IP address for readiness check to avoid DNS lag
             await self._wait_for_readiness(ip)
+            if cold_start_at is not None:
+                await self._record_cold_start(name, image, time.time() - cold_start_at)
             return name
 
     async def _wait_for_readiness(
@@ -177,53 +280,567 @@ class ContainerOrchestrator:
 
         logger.warning(f"Container {host} did not become ready in {timeout}s")
 
+    @staticmethod
+    def _is_oom_killed(container) -> bool:
+        return bool(container.attrs.get("State", {}).get("OOMKilled", False))
+
+    @staticmethod
+    def _function_name_of(container) -> str:
+        """ラベルから関数名を取得（ラベルがない旧コンテナはコンテナ名を使用）"""
+        return (container.labels or {}).get(FUNCTION_LABEL) or container.name
+
+    async def _admit(
+        self, function_name: str, requested: int = 1, exclude: Optional[str] = None
+    ) -> None:
+        """
+        コンテナを requested 個追加で起動できるか判定
+
+        Docker 上で実行中の管理下コンテナ (exclude を除く) を稼働中ワーカーとして予約量を計上する。
+        Heartbeat で通知された名前や、終了済みで未回収のコンテナは計上しない。
+        容量不足の場合は AdmissionRejectedError を送出。
+        """
+        if self.admission is None:
+            return
+        containers = await self._list_managed(filters={"status": "running"})
+        running = sum(1 for container in containers if container.name != exclude)
+        self.admission.admit(function_name, running, requested)
+
+    async def _admit_or_evict(
+        self, function_name: str, requested: int = 1, exclude: Optional[str] = None
+    ) -> None:
+        """
+        _admit で容量不足の場合、EVICTION_ENABLED であればアイドルコンテナを
+        停止スコアの低い順に停止して再判定する（停止しても不足する場合は元の例外を送出）
+        """
+        try:
+            await self._admit(function_name, requested, exclude)
+            return
+        except AdmissionRejectedError:
+            if not config.EVICTION_ENABLED:
+                raise
+            candidates = await self.get_eviction_candidates(exclude=exclude)
+            if not candidates:
+                raise
+
+        for candidate in candidates:
+            logger.info(
+                f"Evicting {candidate.container_name} (score: {candidate.score:.4f}) "
+                f"to admit {function_name}"
+            )
+            if not await self._evict(candidate.container_name):
+                continue
+            try:
+                await self._admit(function_name, requested, exclude)
+                return
+            except AdmissionRejectedError:
+                continue
+        await self._admit(function_name, requested, exclude)
+
+    async def get_eviction_candidates(
+        self, exclude: Optional[str] = None
+    ) -> List[EvictionCandidate]:
+        """
+        容量不足時の停止候補を停止する順に返す
+
+        EVICTION_MIN_IDLE_SECONDS 以上アイドルで、リース中でない管理下のコンテナが対象。
+        アイドル時間は最終アクセス (Heartbeat を含む) からの経過時間のため、プール中の
+        ワーカーは Heartbeat ごとに更新され、HEARTBEAT_INTERVAL より長い最小アイドル時間では
+        対象になりません。
+        """
+        now = time.time()
+        candidates = []
+        for name, last_access in list(self.last_accessed.items()):
+            idle_seconds = now - last_access
+            if (
+                name == exclude
+                or idle_seconds < config.EVICTION_MIN_IDLE_SECONDS
+                or self.leases.is_leased(name, now)
+            ):
+                continue
+            try:
+                container = await self.docker.get_container(name)
+            except docker.errors.NotFound:
+                continue
+            if container.status != "running":
+                continue
+
+            function_name = self._function_name_of(container)
+            stats = self.resource_monitor.functions.get(function_name)
+            memory = stats.peak_memory_bytes if stats and stats.peak_memory_bytes else 0
+            candidates.append(
+                EvictionCandidate(
+                    container_name=name,
+                    function_name=function_name,
+                    idle_seconds=idle_seconds,
+                    memory_bytes=memory or config.WORKER_MEMORY_RESERVATION_MB * 1024 * 1024,
+                    profile=self.profiles.get(function_name),
+                )
+            )
+        return rank_candidates(
+            candidates,
+            cold_start_weight=config.EVICTION_COLD_START_WEIGHT,
+            image_weight=config.EVICTION_IMAGE_WEIGHT,
+        )
+
+    async def _evict(self, container_name: str) -> bool:
+        """
+        アイドル停止と同様にコンテナを停止し、管理対象から外す
+
+        コンテナごとのロックを取得してからアイドル時間とリースを再確認し、
+        候補の選定後にアクセス・リースされたコンテナは停止しない。
+        停止した (または既に存在しない) 場合は True を返す。
+        """
+        async with self._locks_lock:
+            lock = self.locks.setdefault(container_name, asyncio.Lock())
+        if lock.locked():
+            # 起動・停止処理中のコンテナは対象外。ロックを待つと、互いのコンテナを
+            # 退避しようとする要求同士がデッドロックするため待たない
+            return False
+        async with lock:
+            now = time.time()
+            last_access = self.last_accessed.get(container_name)
+            if (
+                last_access is not None and now - last_access < config.EVICTION_MIN_IDLE_SECONDS
+            ) or self.leases.is_leased(container_name, now):
+                return False
+            try:
+                container = await self.docker.get_container(container_name)
+                if container.status == "running":
+                    await self.docker.stop_container(container)
+            except docker.errors.NotFound:
+                pass
+            async with self._locks_lock:
+                self._forget_container(container_name)
+        return True
+
+    async def _record_cold_start(self, function_name: str, image: str, seconds: float) -> None:
+        """コールドスタート時間と (未計測であれば) イメージサイズを記録"""
+        self.profiles.record_cold_start(function_name, seconds)
+        if self.profiles.has_image_size(function_name):
+            return
+        try:
+            size = await self.docker.get_image_size(image)
+            self.profiles.record_image_size(function_name, size)
+        except Exception as e:
+            logger.debug(f"Failed to get image size of {image}: {e}")
+
+    async def handle_docker_event(self, event: Dict) -> None:
+        """
+        Docker イベントのハンドラ (OOM Kill / 異常終了の記録)
+
+        自身が作成したコンテナと、採用ラベルにより取り込んだ外部コンテナのイベントのみ扱います。
+        """
+        action = event.get("Action")
+        if action not in ("oom", "die"):
+            return
+        attributes = event.get("Actor", {}).get("Attributes", {})
+        container_name = attributes.get("name", "")
+        if attributes.get("created_by") != PROJECT_LABEL and container_name not in self.adopted:
+            return
+        function_name = attributes.get(FUNCTION_LABEL) or container_name
+
+        if action == "oom":
+            self.resource_monitor.record_oom(function_name, container_name)
+            self.crash_loop.record_crash(function_name)
+            return
+
+        # die: OOM Kill は oom イベントで記録済み (終了コード 137) のため除外される
+        try:
+            exit_code = int(attributes.get("exitCode", ""))
+        except ValueError:
+            return
+        if is_crash_exit_code(exit_code):
+            logger.warning(f"Container {container_name} exited with code {exit_code}")
+            self.crash_loop.record_crash(function_name)
+
+    async def watch_container_events(self) -> None:
+        """
+        管理下コンテナの OOM / 終了イベントを購読（キャンセルされるまで継続）
+
+        取り込んだ外部コンテナは作成元ラベルを持たないため、ラベルでは絞り込まず
+        handle_docker_event 側で対象を判定します。
+        """
+        await self.docker.listen_events(
+            self.handle_docker_event,
+            filters={"type": "container", "event": ["oom", "die"]},
+        )
+
+    async def collect_memory_stats(self) -> None:
+        """実行中の管理下コンテナのメモリ使用量をサンプリング"""
+        containers = await self._list_managed(filters={"status": "running"})
+        for container in containers:
+            try:
+                stats = await self.docker.get_container_stats(container)
+                usage, limit = extract_memory_usage(stats)
+                # 上限未設定のコンテナの stats の limit はホストのメモリ量のため記録しない
+                if not cgroup_memory_limit(container.attrs):
+                    limit = 0
+                self.resource_monitor.record_memory(
+                    self._function_name_of(container), usage, limit
+                )
+            except Exception as e:
+                logger.debug(f"Failed to collect stats for {container.name}: {e}")
+
+    async def _prepare_volume(self, function_name: str, volume: FunctionVolume) -> Dict:
+        """
+        関数の永続ボリュームを用意し、run_container の volumes 引数を返す
+
+        VOLUME_ROOT 指定時は {VOLUME_ROOT}/{function_name} をボリュームの実体とする。
+        Docker デーモンには VOLUME_HOST_ROOT (未指定時は VOLUME_ROOT) 配下のパスを渡す。
+        """
+        self.volume_quota.set_quota(function_name, volume.size_mb)
+        labels = {"created_by": PROJECT_LABEL, VOLUME_FUNCTION_LABEL: function_name}
+        if volume.size_mb:
+            labels[VOLUME_QUOTA_LABEL] = str(volume.size_mb)
+
+        driver_opts: Dict[str, str] = {}
+        if config.VOLUME_ROOT:
+            # bind 先のディレクトリは事前に存在している必要がある
+            directory = volume_directory_name(function_name)
+            os.makedirs(os.path.join(config.VOLUME_ROOT, directory), exist_ok=True)
+            host_root = config.VOLUME_HOST_ROOT or config.VOLUME_ROOT
+            driver_opts = volume_driver_opts(function_name, host_root)
+
+        name = volume_name(function_name)
+        await self.docker.ensure_volume(name, labels=labels, driver_opts=driver_opts)
+        return {name: {"bind": volume.path, "mode": "rw"}}
+
+    @staticmethod
+    def _dev_code_volume(function_name: str) -> Dict:
+        """DEV_CODE_ROOT 設定時、関数のコードディレクトリを読み取り専用でバインドする volumes 引数"""
+        if not config.DEV_CODE_ROOT:
+            return {}
+        directory = code_directory_name(function_name, config.DEV_CODE_ROOT)
+        if directory is None:
+            return {}
+        host_root = config.DEV_CODE_HOST_ROOT or config.DEV_CODE_ROOT
+        return {
+            os.path.join(host_root, directory): {"bind": config.DEV_CODE_MOUNT_PATH, "mode": "ro"}
+        }
+
+    async def reload_function_code(self, directory: str) -> None:
+        """コードディレクトリが変更された関数の実行中コンテナを再起動"""
+        function_names = {directory, FUNCTION_NAME_PREFIX + directory}
+        containers = await self.docker.list_containers(
+            filters={"label": f"created_by={PROJECT_LABEL}"}
+        )
+        for container in containers:
+            if self._function_name_of(container) not in function_names:
+                continue
+            logger.info(f"Code changed in {directory}, restarting {container.name}")
+            try:
+                await self.docker.restart_container(container, timeout=0)
+            except Exception as e:
+                logger.error(f"Failed to restart {container.name}: {e}")
+
+    async def collect_volume_usage(self) -> None:
+        """関数ボリュームの使用量を計測し、クォータ超過を判定"""
+        try:
+            usage = await self.docker.get_volume_usage(VOLUME_FUNCTION_LABEL)
+        except Exception as e:
+            logger.warning(f"Failed to collect volume usage: {e}")
+            return
+        for function_name, usage_bytes in usage.items():
+            self.volume_quota.record_usage(function_name, usage_bytes)
+
+    def get_volume_stats(self) -> Dict[str, Dict]:
+        """関数ごとのボリューム使用量とクォータ"""
+        return self.volume_quota.snapshot()
+
+    def get_quarantined_functions(self) -> Dict[str, Dict]:
+        """隔離中の関数一覧"""
+        return self.crash_loop.snapshot()
+
+    def clear_quarantine(self, function_name: str) -> bool:
+        """関数の隔離を解除。隔離中だった場合は True"""
+        cleared = self.crash_loop.clear(function_name)
+        if cleared:
+            logger.info(f"Quarantine cleared for {function_name}")
+        return cleared
+
+    def get_resource_stats(self) -> Dict[str, Dict]:
+        """関数ごとの OOM 回数・ピークメモリ・推奨メモリを返す"""
+        return self.resource_monitor.snapshot()
+
+    async def load_image_archive(self, archive_path: str) -> List[str]:
+        """
+        イメージアーカイブを読み込み（レジストリ非接続環境向け）
+        """
+        logger.info(f"Loading image archive: {archive_path}")
+        images = await self.docker.load_image_archive(archive_path)
+        logger.info(f"Loaded images from {archive_path}: {images}")
+        return images
+
+    async def plan_function_sync(
+        self, specs: List[FunctionSpec], prune: bool = False
+    ) -> List[Dict]:
+        """
+        あるべき関数一覧と管理下のコンテナを比較し、適用する操作の一覧を返す
+
+        - pull: 各関数のイメージを取得（ローカルにあっても pull し、同じタグの更新を取り込む）
+        - update: イメージ (タグまたはイメージ ID) が変わった関数のコンテナを削除
+          （次回の起動要求で新しいイメージから作成）
+        - delete: 一覧にない関数のコンテナを削除（prune=True の場合のみ、関数名ラベルのあるものに限る）
+
+        pull を先に並べ、イメージ取得中も既存のコンテナで応答できるようにする。
+        pull 前のローカルのイメージ ID を image_id に記録し、apply 時の更新検出に使用する。
+        """
+        desired = {spec.name: spec for spec in specs}
+        plan: List[Dict] = []
+        image_ids: Dict[str, Optional[str]] = {}
+        for spec in specs:
+            image = spec.image or f"{spec.name}:latest"
+            image_ids[image] = await self.docker.get_image_id(image)
+            plan.append(
+                {
+                    "action": "pull",
+                    "function": spec.name,
+                    "target": image,
+                    "platform": spec.platform or config.CONTAINER_PLATFORM,
+                    "image_id": image_ids[image],
+                }
+            )
+
+        containers = await self.docker.list_containers(
+            all=True, filters={"label": f"created_by={PROJECT_LABEL}"}
+        )
+        for container in sorted(containers, key=lambda c: c.name):
+            function_name = self._function_name_of(container)
+            spec = desired.get(function_name)
+            if spec is None:
+                # 関数名ラベルのないコンテナは関数を特定できないため削除しない
+                if prune and (container.labels or {}).get(FUNCTION_LABEL):
+                    plan.append(
+                        {"action": "delete", "function": function_name, "target": container.name}
+                    )
+                continue
+
+            image = spec.image or f"{function_name}:latest"
+            if self._uses_other_image(container, image, image_ids[image]):
+                plan.append(
+                    {"action": "update", "function": function_name, "target": container.name}
+                )
+        return plan
+
+    @staticmethod
+    def _uses_other_image(container, image: str, image_id: Optional[str]) -> bool:
+        """コンテナが image (ローカルの ID が image_id) 以外のイメージから作成されているか"""
+        if container.attrs.get("Config", {}).get("Image") != image:
+            return True
+        # 同じタグでも再プッシュ・再読み込みされていればイメージ ID が異なる
+        return image_id is not None and container.attrs.get("Image") != image_id
+
+    async def apply_function_sync(self, plan: List[Dict]) -> AsyncIterator[Dict]:
+        """
+        plan_function_sync の操作を順に適用し、操作ごとの進捗を返す
+
+        1 つの操作が失敗しても残りの操作は継続する（status: "failed" と error を返す）。
+        pull でイメージ ID が変わった場合、そのイメージを使う既存コンテナの update を
+        末尾に追加する（以降の進捗の total も増える）。
+        update / delete はコンテナごとのロックを取得して行う。
+        """
+        steps = list(plan)
+        targets = {step["target"] for step in steps if step["action"] != "pull"}
+        index = 0
+        while index < len(steps):
+            step = steps[index]
+            index += 1
+            event = {**step, "index": index, "total": len(steps)}
+            try:
+                if step["action"] == "pull":
+                    image = await self.docker.ensure_image(
+                        step["target"], step.get("platform"), pull=True
+                    )
+                    if image.id != step.get("image_id"):
+                        for update in await self._plan_updates_after_pull(step, image.id):
+                            if update["target"] not in targets:
+                                targets.add(update["target"])
+                                steps.append(update)
+                        event["total"] = len(steps)
+                else:
+                    await self._remove_function_container(step["target"])
+                event["status"] = "done"
+            except Exception as e:
+                logger.error(f"Function sync {step['action']} failed for {step['target']}: {e}")
+                event["status"] = "failed"
+                event["error"] = str(e)
+            yield event
+
+    async def _plan_updates_after_pull(self, step: Dict, image_id: str) -> List[Dict]:
+        """pull で更新されたイメージより古いイメージから作成された関数のコンテナの update"""
+        containers = await self.docker.list_containers(
+            all=True, filters={"label": f"created_by={PROJECT_LABEL}"}
+        )
+        return [
+            {"action": "update", "function": step["function"], "target": container.name}
+            for container in sorted(containers, key=lambda c: c.name)
+            if self._function_name_of(container) == step["function"]
+            and self._uses_other_image(container, step["target"], image_id)
+        ]
+
+    async def _remove_function_container(self, name: str) -> None:
+        """コンテナごとのロックを取得してコンテナを停止・削除し、管理情報を破棄"""
+        async with self._locks_lock:
+            lock = self.locks.setdefault(name, asyncio.Lock())
+        async with lock:
+            try:
+                container = await self.docker.get_container(name)
+                await self.docker.stop_container(container)
+                await self.docker.remove_container(container, force=True)
+            except docker.errors.NotFound:
+                pass
+            async with self._locks_lock:
+                self._forget_container(name)
+
+    def _forget_container(self, name: str) -> None:
+        """削除したコンテナの管理情報（アクセス記録・ロック・採用状態・リース）を破棄"""
+        self.last_accessed.pop(name, None)
+        self.locks.pop(name, None)
+        self.adopted.discard(name)
+        self.leases.release_container(name)
+
+    def _find_idle_containers(self, timeout_seconds: int, now: float) -> Dict[str, float]:
+        """アイドル時間がタイムアウトを超えたコンテナ名とアイドル秒数（リース中は除く）"""
+        return {
+            name: now - last_access
+            for name, last_access in list(self.last_accessed.items())  # Iterate copy
+            if now - last_access > timeout_seconds and not self.leases.is_leased(name, now)
+        }
+
+    def grant_lease(self, container_name: str, function_name: str, ttl_seconds: int) -> Lease:
+        """コンテナにリースを発行（既存のリースがあれば延長）"""
+        lease = self.leases.grant(container_name, function_name, ttl_seconds)
+        logger.debug(f"Lease {lease.lease_id} granted for {container_name} ({ttl_seconds}s)")
+        return lease
+
+    def renew_lease(self, lease_id: str, ttl_seconds: Optional[int] = None) -> Lease:
+        """リースを更新（期限切れ・回収済みの場合は LeaseNotFoundError）"""
+        return self.leases.renew(lease_id, ttl_seconds)
+
+    def release_lease(self, lease_id: str) -> Optional[Lease]:
+        """リースを返却（コンテナは通常のアイドル停止の対象に戻る）"""
+        return self.leases.release(lease_id)
+
+    async def reclaim_expired_leases(self) -> None:
+        """期限切れのリースを持つコンテナを停止・削除"""
+        for lease in self.leases.expired():
+            name = lease.container_name
+            # ensure_container_running と競合しないよう、コンテナごとのロックを取得して回収する
+            async with self._locks_lock:
+                lock = self.locks.setdefault(name, asyncio.Lock())
+            async with lock:
+                # ロック待ちの間に更新・再発行されたリースは回収しない
+                if self.leases.is_leased(name):
+                    continue
+                logger.info(f"Lease {lease.lease_id} expired, reclaiming container {name}")
+                self.leases.release(lease.lease_id)
+                try:
+                    container = await self.docker.get_container(name)
+                    await self.docker.stop_container(container)
+                    await self.docker.remove_container(container, force=True)
+                except docker.errors.NotFound:
+                    pass
+                except Exception as e:
+                    logger.error(f"Failed to reclaim container {name}: {e}")
+                    continue
+
+                async with self._locks_lock:
+                    self._forget_container(name)
+
+    async def simulate_idle_cleanup(self, timeout_seconds: int = 900) -> List[Dict]:
+        """
+        stop_idle_containers / reclaim_expired_leases のドライラン
+
+        現在の状態とタイムアウト値で停止対象となるコンテナを返す（停止・削除は行わない）。
+        action は期限切れリースのコンテナなら "reclaim"（停止・削除）、それ以外で running なら
+        "stop"、既に停止済み / 存在しない場合は "untrack"。
+        """
+        now = time.time()
+        idle = self._find_idle_containers(timeout_seconds, now)
+        expired = {lease.container_name for lease in self.leases.expired(now)}
+        candidates = []
+        for name in set(idle) | expired:
+            try:
+                container = await self.docker.get_container(name)
+                status = container.status
+            except docker.errors.NotFound:
+                status = "not_found"
+            if status == "not_found":
+                action = "untrack"
+            elif name in expired:
+                action = "reclaim"
+            else:
+                action = "stop" if status == "running" else "untrack"
+            idle_seconds = idle.get(name, now - self.last_accessed.get(name, now))
+            candidates.append(
+                {
+                    "name": name,
+                    "idle_seconds": int(idle_seconds),
+                    "status": status,
+                    "action": action,
+                }
+            )
+        return sorted(candidates, key=lambda c: c["idle_seconds"], reverse=True)
+
     async def stop_idle_containers(self, timeout_seconds: int = 900) -> None:
         now = time.time()
         to_remove = []
 
-        for name, last_access in list(self.last_accessed.items()):  # Iterate copy
-            if now - last_access > timeout_seconds:
+        for name in self._find_idle_containers(timeout_seconds, now):
+            try:
+                logger.info(f"Scale-down: Stopping idle container {name}")
                 try:
-                    logger.info(f"Scale-down: Stopping idle container {name}")
-                    try:
-                        container = await self.docker.get_container(name)
-                        if container.status == "running":
-                            await self.docker.stop_container(container)
-                    except docker.errors.NotFound:
-                        pass
+                    container = await self.docker.get_container(name)
+                    if container.status == "running":
+                        await self.docker.stop_container(container)
+                except docker.errors.NotFound:
+                    pass
 
-                    to_remove.append(name)
-                except Exception as e:
-                    logger.error(f"Error stopping/checking {name}: {e}", exc_info=True)
+                to_remove.append(name)
+            except Exception as e:
+                logger.error(f"Error stopping/checking {name}: {e}", exc_info=True)
 
         async with self._locks_lock:
             for name in to_remove:
                 if name in self.locks:
                     del self.locks[name]
                 self.last_accessed.pop(name, None)
+                self.adopted.discard(name)
 
         if to_remove:
             logger.info(f"Cleanup completed. Removed: {to_remove}")
 
-    async def stop_container(self, container_id: str) -> None:
+    async def stop_container(
+        self, container_id: str, grace_period: Optional[int] = None, remove: bool = True
+    ) -> None:
         """
         指定されたコンテナを停止
 
-        Gatewayからの明示的な終了要求(Draining)などで使用
+        SIGTERM を送信し、grace_period 秒以内に終了しなければ SIGKILL します
+        （未指定時は CONTAINER_STOP_GRACE_PERIOD）。remove=True の場合は停止後に削除します。
+
+        Gatewayからの明示的な終了要求(Draining)などで使用。
+        コンテナが存在しない場合は docker.errors.NotFound、停止に失敗した場合は
+        Docker のエラーをそのまま送出します (呼び出し側で 404 / 500 に変換)。
         """
         try:
             # DockerAdaptor expects a Container object, not a string ID
             container = await self.docker.get_container(container_id)
-            await self.docker.stop_container(container)
-            await self.docker.remove_container(container, force=True)  # Ensure removal
+            await self.docker.stop_container(container, timeout=grace_period)
+            if remove:
+                await self.docker.remove_container(container, force=True)  # Ensure removal
         except docker.errors.NotFound:
             logger.warning(f"Container {container_id} not found during stop request.")
+            # 既に存在しないコンテナの管理情報は残さない
+            async with self._locks_lock:
+                self._forget_container(container_id)
+            raise
         except Exception as e:
             logger.error(f"Failed to stop container {container_id}: {e}")
+            raise
 
-        # last_accessed からの削除
-        if container_id in self.last_accessed:
-            del self.last_accessed[container_id]
+        async with self._locks_lock:
+            self._forget_container(container_id)
 
     async def list_managed_containers(self) -> List[WorkerInfo]:
         """
@@ -231,9 +848,7 @@ class ContainerOrchestrator:
 
         Gateway起動時のSync(Adoption)に使用
         """
-        containers = await self.docker.list_containers(
-            all=True, filters={"label": f"created_by={PROJECT_LABEL}"}
-        )
+        containers = await self._list_managed(include_stopped=True)
         workers = []
         for c in containers:
             try:
@@ -264,6 +879,7 @@ class ContainerOrchestrator:
                         port=config.LAMBDA_PORT,
                         created_at=created_at,
                         last_used_at=self.last_accessed.get(c.name, 0.0),
+                        adopted=c.name in self.adopted,
                     )
                 )
             except Exception as e:
@@ -318,6 +934,67 @@ class ContainerOrchestrator:
         except Exception as e:
             logger.error(f"Failed to sync with Docker: {e}", exc_info=True)
 
+        await self.adopt_external_containers()
+
+    @staticmethod
+    def _managed_label_filters() -> List[str]:
+        """管理下コンテナのラベルフィルタ (外部コンテナの取り込みが有効なら採用ラベルを含む)"""
+        labels = [f"created_by={PROJECT_LABEL}"]
+        if config.EXTERNAL_ADOPTION_ENABLED:
+            labels.append(config.EXTERNAL_ADOPTION_LABEL)
+        return labels
+
+    async def _list_managed(
+        self, include_stopped: bool = False, filters: Optional[Dict] = None
+    ) -> List:
+        """
+        管理下のコンテナ一覧
+
+        Docker のラベルフィルタは AND 条件のため、ラベルごとに取得して ID で重複を除きます。
+        """
+        containers: Dict[str, object] = {}
+        for label in self._managed_label_filters():
+            found = await self.docker.list_containers(
+                all=include_stopped, filters={**(filters or {}), "label": label}
+            )
+            for container in found:
+                containers.setdefault(container.id, container)
+        return list(containers.values())
+
+    async def adopt_external_containers(self) -> List[str]:
+        """
+        採用ラベルを持つ実行中の外部コンテナを管理下に取り込む
+
+        取り込んだコンテナはアクセス記録に登録され、アイドル停止・コンテナ一覧の対象になります。
+        新たに取り込んだコンテナ名を返します。
+        """
+        if not config.EXTERNAL_ADOPTION_ENABLED:
+            return []
+        try:
+            containers = await self.docker.list_containers(
+                filters={"label": config.EXTERNAL_ADOPTION_LABEL, "status": "running"}
+            )
+        except Exception as e:
+            logger.error(f"Failed to discover external containers: {e}")
+            return []
+
+        now = time.time()
+        adopted = []
+        for container in containers:
+            name = container.name
+            if name in self.last_accessed:
+                continue
+            if not follows_naming_convention(name):
+                logger.warning(
+                    f"External container {name} does not follow lambda-{{function}}-{{suffix}} "
+                    "naming; Gateway will not route requests to it"
+                )
+            self.last_accessed[name] = now
+            self.adopted.add(name)
+            adopted.append(name)
+            logger.info(f"Adopted external container: {name}")
+        return adopted
+
     async def shutdown(self):
         """Clean up resources (HTTP client, thread pools, etc.)"""
         logger.info("Shutting down ContainerOrchestrator...")
@@ -336,16 +1013,36 @@ class ContainerOrchestrator:
         count: int = 1,
         image: Optional[str] = None,
         env: Optional[Dict[str, str]] = None,
+        platform: Optional[str] = None,
+        env_overrides: Optional[Dict[str, str]] = None,
+        volume: Optional[FunctionVolume] = None,
+        security: Optional[ContainerSecurity] = None,
     ) -> List[WorkerInfo]:
         """
         指定された数のコンテナをプロビジョニング
 
         コンテナ名: lambda-{function_name}-{uuid4()[:8]}
         """
+        self.crash_loop.check(function_name)
+        self.volume_quota.check(function_name)
+        await self._admit_or_evict(function_name, requested=count)
         workers: List[WorkerInfo] = []
 
         if image is None:
             image = f"{function_name}:latest"
+        platform = platform or config.CONTAINER_PLATFORM
+
+        run_kwargs = {**build_security_options(security), **build_clock_options()}
+        if volume:
+            volumes = await self._prepare_volume(function_name, volume)
+            run_kwargs.setdefault("volumes", {}).update(volumes)
+        dev_code = self._dev_code_volume(function_name)
+        if dev_code:
+            run_kwargs.setdefault("volumes", {}).update(dev_code)
+        if platform:
+            # バッチ内で共通のため、ループ前に一度だけ確認する
+            await self.docker.ensure_image(image, platform)
+            run_kwargs["platform"] = platform
 
         try:
             for _ in range(count):
@@ -354,17 +1051,9 @@ class ContainerOrchestrator:
 
                 # Cold start - 新規コンテナ作成
                 logger.info(f"Provisioning container: {container_name}")
+                cold_start_at = time.time()
 
-                container_env = (env or {}).copy()
-                container_env["PYTHONUNBUFFERED"] = "1"
-
-                # VictoriaLogs URL を Lambda に注入
-                import os
-
-                vl_host = os.environ.get("VICTORIALOGS_HOST", "victorialogs")
-                vl_port = os.environ.get("VICTORIALOGS_PORT", "9428")
-                container_env["VICTORIALOGS_URL"] = f"http://{vl_host}:{vl_port}/insert/jsonline"
-                container_env["AWS_LAMBDA_FUNCTION_NAME"] = function_name
+                container_env = resolve_environment(function_name, env, env_overrides)
 
                 container = await self.docker.run_container(
                     image,
@@ -373,7 +1062,8 @@ class ContainerOrchestrator:
                     environment=container_env,
                     network=self.network,
                     restart_policy={"Name": "no"},
-                    labels={"created_by": PROJECT_LABEL},
+                    labels={"created_by": PROJECT_LABEL, FUNCTION_LABEL: function_name},
+                    **run_kwargs,
                 )
 
                 # Reload container to get IP
@@ -387,6 +1077,7 @@ class ContainerOrchestrator:
 
                 # Wait for readiness
                 await self._wait_for_readiness(ip)
+                await self._record_cold_start(function_name, image, time.time() - cold_start_at)
 
                 # Track last access
                 self.last_accessed[container_name] = time.time()
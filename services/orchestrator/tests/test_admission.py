@@ -0,0 +1,83 @@
+"""
+Tests for AdmissionController (ホスト容量に基づく起動可否判定)
+"""
+
+from unittest.mock import patch
+
+import pytest
+
+from services.orchestrator.admission import (
+    AdmissionController,
+    AdmissionRejectedError,
+    HostCapacity,
+    read_host_capacity,
+)
+
+MB = 1024 * 1024
+
+
+def _write(path, content):
+    path.parent.mkdir(parents=True, exist_ok=True)
+    path.write_text(content)
+
+
+def test_read_host_capacity_uses_cgroup_v2_limits(tmp_path):
+    """cgroup v2 の上限が /proc より小さい場合は cgroup の値を採用"""
+    _write(tmp_path / "proc" / "meminfo", "MemTotal:       8388608 kB\nMemFree: 1 kB\n")
+    _write(tmp_path / "cgroup" / "memory.max", f"{2048 * MB}\n")
+    _write(tmp_path / "cgroup" / "cpu.max", "150000 100000\n")
+
+    with patch("services.orchestrator.admission.os.cpu_count", return_value=8):
+        capacity = read_host_capacity(str(tmp_path / "proc"), str(tmp_path / "cgroup"))
+
+    assert capacity.memory_bytes == 2048 * MB
+    assert capacity.cpus == 1.5
+
+
+def test_read_host_capacity_unlimited_cgroup(tmp_path):
+    """cgroup が無制限 (max) の場合は /proc の値を使用"""
+    _write(tmp_path / "proc" / "meminfo", "MemTotal:       1048576 kB\n")
+    _write(tmp_path / "cgroup" / "memory.max", "max\n")
+    _write(tmp_path / "cgroup" / "cpu.max", "max 100000\n")
+
+    capacity = read_host_capacity(str(tmp_path / "proc"), str(tmp_path / "cgroup"))
+
+    assert capacity.memory_bytes == 1024 * MB
+    assert capacity.cpus >= 1
+
+
+def test_admit_within_capacity():
+    controller = AdmissionController(
+        HostCapacity(memory_bytes=1024 * MB, cpus=2),
+        memory_per_worker=256 * MB,
+        cpus_per_worker=0.5,
+    )
+    controller.admit("func", running=3, requested=1)
+
+
+def test_admit_rejects_with_shortfall():
+    """容量を超える場合は不足量を含めて拒否"""
+    controller = AdmissionController(
+        HostCapacity(memory_bytes=1024 * MB, cpus=2),
+        memory_per_worker=256 * MB,
+        cpus_per_worker=0.5,
+    )
+
+    with pytest.raises(AdmissionRejectedError) as exc_info:
+        controller.admit("func", running=3, requested=2)
+
+    assert exc_info.value.shortfall == {"memory_bytes": 256 * MB, "cpus": 0.5}
+
+
+def test_admit_respects_overcommit_factor():
+    controller = AdmissionController(
+        HostCapacity(memory_bytes=1024 * MB, cpus=2),
+        memory_per_worker=256 * MB,
+        cpus_per_worker=0.5,
+        overcommit_factor=2.0,
+    )
+    controller.admit("func", running=6, requested=2)
+    assert controller.shortfall(running=8, requested=1) == {
+        "memory_bytes": 256 * MB,
+        "cpus": 0.5,
+    }
@@ -0,0 +1,12 @@
+"""
+Tests for external container adoption (外部コンテナの取り込み規約)
+"""
+
+from services.orchestrator.adoption import follows_naming_convention
+
+
+def test_follows_naming_convention():
+    assert follows_naming_convention("lambda-echo-manual1")
+    assert follows_naming_convention("lambda-s3-test-1a2b3c4d")
+    assert not follows_naming_convention("lambda-echo")
+    assert not follows_naming_convention("echo-manual1")
@@ -1,5 +1,7 @@
+import docker.errors
 import pytest
-from unittest.mock import AsyncMock, MagicMock
+from httpx import AsyncClient, ASGITransport
+from unittest.mock import AsyncMock, MagicMock, patch
 from services.orchestrator.service import ContainerOrchestrator
 from services.common.models.internal import WorkerInfo
 
@@ -9,19 +11,46 @@ async def test_orchestrator_stop_container():
     """Test stop_container method (Phase 2)"""
     orchestrator = ContainerOrchestrator()
     orchestrator.docker = MagicMock()
-    # Mock container info
-    orchestrator.docker.params = {"c1": {"name": "lambda-func-1", "id": "c1"}}
+    container = MagicMock()
+    orchestrator.docker.get_container = AsyncMock(return_value=container)
 
     # Mock stop_container (docker adaptor)
     orchestrator.docker.stop_container = AsyncMock()
+    orchestrator.docker.remove_container = AsyncMock()
 
     # Execute
     await orchestrator.stop_container("c1")
 
     # Verify
-    orchestrator.docker.stop_container.assert_called_once_with("c1")
-    # Verify cleanup from internal state if any (though Orchestrator mainly relies on Docker state?)
-    # The specification implies Orchestrator might track managed containers.
+    orchestrator.docker.get_container.assert_awaited_once_with("c1")
+    orchestrator.docker.stop_container.assert_awaited_once_with(container, timeout=None)
+    orchestrator.docker.remove_container.assert_awaited_once_with(container, force=True)
+
+
+@pytest.mark.asyncio
+@pytest.mark.parametrize(
+    "error, status_code",
+    [
+        (docker.errors.NotFound("Not found"), 404),
+        (docker.errors.APIError("boom"), 500),
+    ],
+)
+async def test_stop_endpoint_maps_errors(error, status_code):
+    """POST /containers/{id}/stop は停止失敗を 404 / 500 で返す"""
+    from services.orchestrator.main import app
+
+    manager = MagicMock()
+    manager.stop_container = AsyncMock(side_effect=error)
+
+    transport = ASGITransport(app=app)  # type: ignore
+    with patch("services.orchestrator.main.orchestrator", manager):
+        async with AsyncClient(transport=transport, base_url="http://test") as client:
+            response = await client.post("/containers/lambda-hello-1234/stop")
+
+    assert response.status_code == status_code
+    manager.stop_container.assert_awaited_once_with(
+        "lambda-hello-1234", grace_period=None, remove=False
+    )
 
 
 @pytest.mark.asyncio
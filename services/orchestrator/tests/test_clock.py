@@ -0,0 +1,70 @@
+"""
+Tests for container clock and timezone injection (タイムゾーン・時刻参照デバイス)
+"""
+
+import os
+from unittest.mock import patch
+
+from services.orchestrator.clock import (
+    CLOCK_DEVICE_ENV,
+    build_clock_options,
+    clock_environment,
+    detect_host_timezone,
+    resolve_timezone,
+)
+
+
+def test_detect_host_timezone_prefers_tz_env(tmp_path):
+    (tmp_path / "timezone").write_text("Europe/Berlin\n")
+
+    assert detect_host_timezone({"TZ": ":Asia/Tokyo"}, str(tmp_path)) == "Asia/Tokyo"
+
+
+def test_detect_host_timezone_from_etc_timezone(tmp_path):
+    (tmp_path / "timezone").write_text("Europe/Berlin\n")
+
+    assert detect_host_timezone({}, str(tmp_path)) == "Europe/Berlin"
+
+
+def test_detect_host_timezone_from_localtime_symlink(tmp_path):
+    os.symlink("/usr/share/zoneinfo/America/New_York", tmp_path / "localtime")
+
+    assert detect_host_timezone({}, str(tmp_path)) == "America/New_York"
+
+
+def test_detect_host_timezone_unknown(tmp_path):
+    assert detect_host_timezone({}, str(tmp_path)) is None
+
+
+def test_resolve_timezone():
+    assert resolve_timezone(None) is None
+    assert resolve_timezone("Asia/Tokyo") == "Asia/Tokyo"
+    with patch(
+        "services.orchestrator.clock.detect_host_timezone", return_value="Europe/Paris"
+    ):
+        assert resolve_timezone("host") == "Europe/Paris"
+
+
+def test_clock_environment_and_options():
+    with patch("services.orchestrator.clock.config") as mock_config:
+        mock_config.CONTAINER_TIMEZONE = "Asia/Tokyo"
+        mock_config.CONTAINER_MOUNT_LOCALTIME = True
+        mock_config.CONTAINER_CLOCK_DEVICES = ["/dev/ptp0"]
+        env = clock_environment()
+        options = build_clock_options()
+
+    assert env == {"TZ": "Asia/Tokyo", CLOCK_DEVICE_ENV: "/dev/ptp0"}
+    assert options == {
+        "volumes": {"/etc/localtime": {"bind": "/etc/localtime", "mode": "ro"}},
+        "devices": ["/dev/ptp0:/dev/ptp0:r"],
+    }
+
+
+def test_clock_defaults_inject_nothing():
+    with patch("services.orchestrator.clock.config") as mock_config:
+        mock_config.CONTAINER_TIMEZONE = None
+        mock_config.CONTAINER_MOUNT_LOCALTIME = False
+        mock_config.CONTAINER_CLOCK_DEVICES = []
+
+        assert clock_environment() == {}
+        assert build_clock_options() == {}
@@ -0,0 +1,70 @@
+"""
+Tests for CrashLoopDetector (クラッシュループ検出と隔離)
+"""
+
+import pytest
+from unittest.mock import patch
+
+from services.orchestrator.crash_loop import (
+    CrashLoopDetector,
+    FunctionQuarantinedError,
+    is_crash_exit_code,
+)
+
+
+def test_is_crash_exit_code():
+    """正常終了・SIGTERM・SIGKILL による停止はクラッシュとみなさない"""
+    assert is_crash_exit_code(1)
+    assert is_crash_exit_code(127)
+    assert not is_crash_exit_code(0)
+    assert not is_crash_exit_code(137)
+    assert not is_crash_exit_code(143)
+    assert not is_crash_exit_code(None)
+
+
+def test_quarantine_after_threshold():
+    detector = CrashLoopDetector(threshold=3, window_seconds=60, cooldown=300)
+
+    detector.record_crash("hello")
+    detector.record_crash("hello")
+    detector.check("hello")  # しきい値未満なので例外なし
+
+    detector.record_crash("hello")
+    with pytest.raises(FunctionQuarantinedError) as excinfo:
+        detector.check("hello")
+
+    assert "QUARANTINED" in str(excinfo.value)
+    assert excinfo.value.crashes == 3
+    assert "hello" in detector.snapshot()
+
+
+def test_crashes_outside_window_are_ignored():
+    detector = CrashLoopDetector(threshold=2, window_seconds=10, cooldown=300)
+
+    with patch("services.orchestrator.crash_loop.time.time", return_value=1000.0):
+        detector.record_crash("hello")
+    with patch("services.orchestrator.crash_loop.time.time", return_value=1020.0):
+        detector.record_crash("hello")
+        detector.check("hello")
+
+
+def test_quarantine_expires_after_cooldown():
+    detector = CrashLoopDetector(threshold=1, window_seconds=60, cooldown=30)
+
+    with patch("services.orchestrator.crash_loop.time.time", return_value=1000.0):
+        detector.record_crash("hello")
+        with pytest.raises(FunctionQuarantinedError):
+            detector.check("hello")
+
+    with patch("services.orchestrator.crash_loop.time.time", return_value=1031.0):
+        detector.check("hello")
+        assert detector.snapshot() == {}
+
+
+def test_clear_quarantine():
+    detector = CrashLoopDetector(threshold=1)
+    detector.record_crash("hello")
+
+    assert detector.clear("hello") is True
+    detector.check("hello")
+    assert detector.clear("hello") is False
@@ -1,5 +1,5 @@
 import pytest
-from unittest.mock import Mock, patch, AsyncMock
+from unittest.mock import MagicMock, Mock, patch, AsyncMock
 from services.orchestrator.docker_adaptor import DockerAdaptor
 
 
@@ -101,3 +101,158 @@ def test_docker_adaptor_has_shutdown_method():
             adaptor = DockerAdaptor()
             assert hasattr(adaptor, "shutdown")
             assert callable(adaptor.shutdown)
+
+
+@pytest.mark.asyncio
+async def test_ensure_image_uses_local_image_when_platform_matches():
+    """ローカルイメージのプラットフォームが一致すれば pull しない"""
+    with patch("services.orchestrator.docker_adaptor.docker.from_env") as mock_docker:
+        mock_client = Mock()
+        mock_docker.return_value = mock_client
+        local_image = Mock(attrs={"Os": "linux", "Architecture": "arm64"})
+        mock_client.images.get.return_value = local_image
+
+        adaptor = DockerAdaptor()
+        result = await adaptor.ensure_image("func:latest", "linux/arm64/v8")
+
+        assert result is local_image
+        mock_client.images.pull.assert_not_called()
+
+
+@pytest.mark.asyncio
+async def test_ensure_image_pull_refreshes_local_tag():
+    """pull=True ではローカルにあっても pull し、失敗時はローカルのイメージを使う"""
+    import docker.errors
+
+    with patch("services.orchestrator.docker_adaptor.docker.from_env") as mock_docker:
+        mock_client = Mock()
+        mock_docker.return_value = mock_client
+        local_image = Mock(attrs={"Os": "linux", "Architecture": "amd64"})
+        pulled_image = Mock(attrs={"Os": "linux", "Architecture": "amd64"})
+        mock_client.images.get.return_value = local_image
+        mock_client.images.pull.return_value = pulled_image
+
+        adaptor = DockerAdaptor()
+        assert await adaptor.ensure_image("func:latest", pull=True) is pulled_image
+        mock_client.images.pull.assert_called_once_with("func:latest", platform=None)
+
+        mock_client.images.pull.side_effect = docker.errors.APIError("registry unreachable")
+        assert await adaptor.ensure_image("func:latest", pull=True) is local_image
+
+
+@pytest.mark.asyncio
+async def test_ensure_image_pulls_requested_platform_on_mismatch():
+    """ローカルイメージのアーキテクチャが異なる場合は指定プラットフォームで pull する"""
+    with patch("services.orchestrator.docker_adaptor.docker.from_env") as mock_docker:
+        mock_client = Mock()
+        mock_docker.return_value = mock_client
+        mock_client.images.get.return_value = Mock(
+            attrs={"Os": "linux", "Architecture": "amd64"}
+        )
+        pulled_image = Mock(attrs={"Os": "linux", "Architecture": "arm64"})
+        mock_client.images.pull.return_value = pulled_image
+
+        adaptor = DockerAdaptor()
+        result = await adaptor.ensure_image("func:latest", "linux/arm64")
+
+        assert result is pulled_image
+        mock_client.images.pull.assert_called_once_with("func:latest", platform="linux/arm64")
+
+
+@pytest.mark.asyncio
+async def test_ensure_image_raises_when_manifest_lacks_platform():
+    """マニフェストに該当プラットフォームがない場合は ImagePlatformError"""
+    import docker.errors
+    from services.orchestrator.docker_adaptor import ImagePlatformError
+
+    with patch("services.orchestrator.docker_adaptor.docker.from_env") as mock_docker:
+        mock_client = Mock()
+        mock_docker.return_value = mock_client
+        mock_client.images.get.side_effect = docker.errors.ImageNotFound("missing")
+        mock_client.images.pull.side_effect = docker.errors.APIError(
+            "pull failed",
+            explanation="no matching manifest for linux/arm64 in the manifest list entries",
+        )
+
+        adaptor = DockerAdaptor()
+        with pytest.raises(ImagePlatformError) as excinfo:
+            await adaptor.ensure_image("func:latest", "linux/arm64")
+
+        assert "linux/arm64" in str(excinfo.value)
+        assert "func:latest" in str(excinfo.value)
+
+
+@pytest.mark.asyncio
+async def test_load_image_archive_returns_tags(tmp_path):
+    """アーカイブ読み込み後、タグ（タグなしの場合は ID）を返す"""
+    archive = tmp_path / "images.tar"
+    archive.write_bytes(b"tar-bytes")
+
+    with patch("services.orchestrator.docker_adaptor.docker.from_env") as mock_docker:
+        mock_client = Mock()
+        mock_docker.return_value = mock_client
+        mock_client.images.load.return_value = [
+            Mock(tags=["hello:latest", "hello:v1"], id="sha256:aaa"),
+            Mock(tags=[], id="sha256:bbb"),
+        ]
+
+        adaptor = DockerAdaptor()
+        images = await adaptor.load_image_archive(str(archive))
+
+        assert images == ["hello:latest", "hello:v1", "sha256:bbb"]
+
+
+@pytest.mark.asyncio
+async def test_stop_container_uses_configured_grace_period():
+    """timeout 未指定時は CONTAINER_STOP_GRACE_PERIOD で SIGTERM -> SIGKILL"""
+    with patch("services.orchestrator.docker_adaptor.docker.from_env"):
+        with patch("services.orchestrator.docker_adaptor.config") as mock_config:
+            mock_config.DOCKER_MAX_WORKERS = 5
+            mock_config.DOCKER_CLIENT_TIMEOUT = 30
+            mock_config.CONTAINER_STOP_GRACE_PERIOD = 15
+            adaptor = DockerAdaptor()
+
+            container = Mock()
+            await adaptor.stop_container(container)
+            container.stop.assert_called_once_with(timeout=15)
+
+            container.reset_mock()
+            await adaptor.stop_container(container, timeout=0)
+            container.stop.assert_called_once_with(timeout=0)
+
+
+@pytest.mark.asyncio
+async def test_listen_events_reconnects_after_stream_ends():
+    """イベントストリームが切断されたらバックオフ後に再接続する"""
+    import asyncio
+
+    received = []
+    done = asyncio.Event()
+
+    async def handler(event):
+        received.append(event)
+        if len(received) == 2:
+            done.set()
+
+    with patch("services.orchestrator.docker_adaptor.docker.from_env") as mock_from_env:
+        mock_client = mock_from_env.return_value
+        mock_client.events.side_effect = [
+            MagicMock(__iter__=Mock(return_value=iter([{"id": "a"}]))),
+            ConnectionError("daemon restarted"),
+            MagicMock(__iter__=Mock(return_value=iter([{"id": "b"}]))),
+            MagicMock(__iter__=Mock(return_value=iter([]))),
+        ]
+        adaptor = DockerAdaptor()
+
+        task = asyncio.create_task(
+            adaptor.listen_events(handler, reconnect_delay=0.01, max_reconnect_delay=0.02)
+        )
+        try:
+            await asyncio.wait_for(done.wait(), timeout=2)
+        finally:
+            task.cancel()
+            with pytest.raises(asyncio.CancelledError):
+                await task
+
+    assert received == [{"id": "a"}, {"id": "b"}]
+    assert mock_client.events.call_count >= 3
@@ -0,0 +1,62 @@
+"""
+Tests for layered environment resolution (環境変数の合成とマスキング)
+"""
+
+from unittest.mock import patch
+
+from services.orchestrator.environment import (
+    REDACTED,
+    redact_environment,
+    resolve_environment,
+)
+
+
+def test_resolve_environment_precedence():
+    """defaults < function env < overrides の順に上書きされる"""
+    defaults = {"AWS_REGION": "ap-northeast-1", "HTTP_PROXY": "http://proxy:3128", "LEVEL": "a"}
+    with patch("services.orchestrator.environment.config") as mock_config:
+        mock_config.CONTAINER_DEFAULT_ENV = defaults
+        env = resolve_environment(
+            "my-func",
+            function_env={"LEVEL": "b", "TABLE": "users"},
+            overrides={"TABLE": "users-test"},
+        )
+
+    assert env["AWS_REGION"] == "ap-northeast-1"
+    assert env["HTTP_PROXY"] == "http://proxy:3128"
+    assert env["LEVEL"] == "b"
+    assert env["TABLE"] == "users-test"
+    # 入力の辞書は変更されない
+    assert defaults["LEVEL"] == "a"
+
+
+def test_resolve_environment_system_vars_win():
+    """システム変数は関数設定や上書きでは変更できない"""
+    with patch("services.orchestrator.environment.config") as mock_config:
+        mock_config.CONTAINER_DEFAULT_ENV = {}
+        env = resolve_environment(
+            "my-func",
+            function_env={"AWS_LAMBDA_FUNCTION_NAME": "spoofed"},
+            overrides={"PYTHONUNBUFFERED": "0"},
+        )
+
+    assert env["AWS_LAMBDA_FUNCTION_NAME"] == "my-func"
+    assert env["PYTHONUNBUFFERED"] == "1"
+    assert env["VICTORIALOGS_URL"].endswith("/insert/jsonline")
+
+
+def test_redact_environment_masks_secret_like_keys():
+    env = {
+        "DB_PASSWORD": "p@ss",
+        "AWS_SECRET_ACCESS_KEY": "abc",
+        "api_token": "xyz",
+        "TABLE": "users",
+    }
+    redacted = redact_environment(env)
+
+    assert redacted["DB_PASSWORD"] == REDACTED
+    assert redacted["AWS_SECRET_ACCESS_KEY"] == REDACTED
+    assert redacted["api_token"] == REDACTED
+    assert redacted["TABLE"] == "users"
+    # 元の辞書は変更されない
+    assert env["DB_PASSWORD"] == "p@ss"
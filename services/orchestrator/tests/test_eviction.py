@@ -0,0 +1,70 @@
+"""
+Tests for eviction cost model (再起動コストに基づく停止順)
+"""
+
+from services.orchestrator.eviction import (
+    EvictionCandidate,
+    FunctionProfile,
+    ProfileStore,
+    rank_candidates,
+)
+
+MB = 1024 * 1024
+
+
+def _candidate(name, idle_seconds=60, memory_mb=128, cold_start=1.0, image_mb=0):
+    return EvictionCandidate(
+        container_name=name,
+        function_name=name,
+        idle_seconds=idle_seconds,
+        memory_bytes=memory_mb * MB,
+        profile=FunctionProfile(cold_start_seconds=cold_start, image_bytes=image_mb * MB),
+    )
+
+
+def test_cheap_to_restart_is_evicted_first():
+    """アイドル時間が同じなら、コールドスタートが速い関数から停止する"""
+    ranked = rank_candidates(
+        [_candidate("java", cold_start=8.0), _candidate("python", cold_start=0.5)]
+    )
+
+    assert [c.container_name for c in ranked] == ["python", "java"]
+
+
+def test_large_image_raises_restart_cost():
+    ranked = rank_candidates(
+        [_candidate("big", image_mb=4096), _candidate("small", image_mb=100)]
+    )
+
+    assert [c.container_name for c in ranked] == ["small", "big"]
+
+
+def test_memory_hog_and_long_idle_are_preferred():
+    ranked = rank_candidates(
+        [
+            _candidate("small-mem", memory_mb=128),
+            _candidate("large-mem", memory_mb=1024),
+        ]
+    )
+    assert ranked[0].container_name == "large-mem"
+
+    ranked = rank_candidates(
+        [_candidate("recent", idle_seconds=30), _candidate("stale", idle_seconds=3600)]
+    )
+    assert ranked[0].container_name == "stale"
+
+
+def test_profile_store_smooths_cold_start():
+    store = ProfileStore()
+    assert store.get("fn").cold_starts == 0
+
+    store.record_cold_start("fn", 2.0)
+    assert store.get("fn").cold_start_seconds == 2.0
+
+    store.record_cold_start("fn", 4.0)
+    assert store.get("fn").cold_start_seconds == 2.0 + 0.3 * 2.0
+    assert store.get("fn").cold_starts == 2
+
+    assert not store.has_image_size("fn")
+    store.record_image_size("fn", 300 * MB)
+    assert store.has_image_size("fn")
@@ -0,0 +1,133 @@
+"""
+Tests for fault injection wrapper (障害注入)
+"""
+
+from unittest.mock import AsyncMock, patch
+
+import pytest
+
+from services.orchestrator.faults import (
+    FaultInjectingAdaptor,
+    FaultRule,
+    InjectedFaultError,
+    parse_rules,
+)
+
+
+class StubAdaptor:
+    def __init__(self):
+        self.calls = []
+        self.network = "test-net"
+
+    async def run_container(self, image, **kwargs):
+        self.calls.append(("run_container", image))
+        return "container"
+
+    async def stop_container(self, container, timeout=None):
+        self.calls.append(("stop_container", container))
+
+
+@pytest.mark.asyncio
+async def test_fail_skips_operation():
+    stub = StubAdaptor()
+    adaptor = FaultInjectingAdaptor(stub, [FaultRule("run_container", "fail")])
+
+    with pytest.raises(InjectedFaultError):
+        await adaptor.run_container("img")
+
+    assert stub.calls == []
+
+
+@pytest.mark.asyncio
+async def test_partial_runs_operation_then_fails():
+    stub = StubAdaptor()
+    adaptor = FaultInjectingAdaptor(stub, [FaultRule("run_container", "partial")])
+
+    with pytest.raises(InjectedFaultError) as excinfo:
+        await adaptor.run_container("img")
+
+    assert stub.calls == [("run_container", "img")]
+    assert excinfo.value.action == "partial"
+
+
+@pytest.mark.asyncio
+async def test_delay_then_runs_operation():
+    stub = StubAdaptor()
+    adaptor = FaultInjectingAdaptor(
+        stub, [FaultRule("stop_container", "delay", delay_seconds=2.5)]
+    )
+
+    with patch("services.orchestrator.faults.asyncio.sleep", AsyncMock()) as mock_sleep:
+        await adaptor.stop_container("c1")
+
+    mock_sleep.assert_awaited_once_with(2.5)
+    assert stub.calls == [("stop_container", "c1")]
+
+
+@pytest.mark.asyncio
+async def test_unconfigured_operations_pass_through():
+    stub = StubAdaptor()
+    adaptor = FaultInjectingAdaptor(stub, [FaultRule("run_container", "fail")])
+
+    await adaptor.stop_container("c1")
+
+    assert stub.calls == [("stop_container", "c1")]
+    assert adaptor.network == "test-net"
+
+
+@pytest.mark.asyncio
+async def test_max_count_limits_injections():
+    stub = StubAdaptor()
+    adaptor = FaultInjectingAdaptor(stub, [FaultRule("run_container", "fail", max_count=1)])
+
+    with pytest.raises(InjectedFaultError):
+        await adaptor.run_container("img")
+    assert await adaptor.run_container("img") == "container"
+    assert adaptor.snapshot() == [
+        {"operation": "run_container", "action": "fail", "injected": 1}
+    ]
+
+
+async def _outcomes(seed: int):
+    adaptor = FaultInjectingAdaptor(
+        StubAdaptor(), [FaultRule("run_container", "fail", probability=0.5)], seed=seed
+    )
+    outcomes = []
+    for _ in range(20):
+        try:
+            await adaptor.run_container("img")
+            outcomes.append(True)
+        except InjectedFaultError:
+            outcomes.append(False)
+    return outcomes
+
+
+@pytest.mark.asyncio
+async def test_same_seed_is_deterministic():
+    first = await _outcomes(seed=42)
+
+    assert first == await _outcomes(seed=42)
+    assert True in first and False in first
+
+
+def test_parse_rules():
+    rules = parse_rules([{"operation": "run_container", "action": "partial"}])
+    assert rules == [FaultRule("run_container", "partial")]
+
+    with pytest.raises(ValueError):
+        parse_rules([{"operation": "run_container", "action": "explode"}])
+
+
+def test_parse_rules_rejects_unknown_or_missing_keys():
+    """設定ミスは TypeError ではなく、問題のキーを示す ValueError にする"""
+    with pytest.raises(ValueError, match="'probabilty'"):
+        parse_rules([{"operation": "run_container", "probabilty": 0.5}])
+
+    with pytest.raises(ValueError, match="'injected'"):
+        parse_rules([{"operation": "run_container", "injected": 3}])
+
+    with pytest.raises(ValueError, match="'operation'"):
+        parse_rules([{"action": "fail"}])
+
+    with pytest.raises(ValueError, match="#0"):
+        parse_rules(["run_container"])
@@ -0,0 +1,56 @@
+"""
+Tests for hot code reload (開発用のコード変更監視)
+"""
+
+import asyncio
+import os
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from services.orchestrator.hot_reload import (
+    CodeChangeHandler,
+    changed_directory_name,
+    code_directory_name,
+)
+
+
+def test_code_directory_name(tmp_path):
+    (tmp_path / "echo").mkdir()
+    (tmp_path / "lambda-s3").mkdir()
+
+    assert code_directory_name("lambda-echo", str(tmp_path)) == "echo"
+    assert code_directory_name("lambda-s3", str(tmp_path)) == "lambda-s3"
+    assert code_directory_name("lambda-unknown", str(tmp_path)) is None
+
+
+def test_changed_directory_name(tmp_path):
+    root = str(tmp_path)
+
+    assert changed_directory_name(os.path.join(root, "echo", "app.py"), root) == "echo"
+    assert changed_directory_name(os.path.join(root, "echo", "pkg", "mod.py"), root) == "echo"
+    # root 直下のファイル・キャッシュ・root 外は対象外
+    assert changed_directory_name(os.path.join(root, "README.md"), root) is None
+    assert changed_directory_name(os.path.join(root, "echo", "__pycache__", "a.pyc"), root) is None
+    assert changed_directory_name("/elsewhere/echo/app.py", root) is None
+
+
+@pytest.mark.asyncio
+async def test_handler_debounces_events_per_directory(tmp_path):
+    """連続した変更はディレクトリごとに 1 回のコールバックにまとめる"""
+    callback = AsyncMock()
+    handler = CodeChangeHandler(
+        str(tmp_path), asyncio.get_running_loop(), callback, debounce=0.05
+    )
+
+    for name in ("a.py", "b.py", "a.py"):
+        event = MagicMock(is_directory=False, event_type="modified")
+        event.src_path = str(tmp_path / "echo" / name)
+        handler.on_any_event(event)
+    event = MagicMock(is_directory=False, event_type="created")
+    event.src_path = str(tmp_path / "s3" / "app.py")
+    handler.on_any_event(event)
+
+    await asyncio.sleep(0.2)
+
+    assert sorted(call.args[0] for call in callback.await_args_list) == ["echo", "s3"]
@@ -0,0 +1,132 @@
+"""
+Tests for Image Archive Load API (POST /images/load)
+"""
+
+import pytest
+from unittest.mock import AsyncMock, MagicMock, patch
+from httpx import AsyncClient, ASGITransport
+
+
+class TestImageLoadEndpoint:
+    """Tests for POST /images/load endpoint"""
+
+    @pytest.fixture
+    def mock_manager(self):
+        manager = MagicMock()
+        manager.load_image_archive = AsyncMock(return_value=["hello-world:latest"])
+        return manager
+
+    @pytest.mark.asyncio
+    async def test_load_uploaded_archive(self, mock_manager):
+        """リクエストボディの tar を一時ファイル経由で読み込む"""
+        from services.orchestrator.main import app
+
+        loaded = {}
+
+        async def capture(archive_path):
+            with open(archive_path, "rb") as f:
+                loaded["data"] = f.read()
+            return ["hello-world:latest"]
+
+        mock_manager.load_image_archive = AsyncMock(side_effect=capture)
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            async with AsyncClient(transport=transport, base_url="http://test") as client:
+                response = await client.post("/images/load", content=b"tar-bytes")
+
+        assert response.status_code == 200
+        assert response.json() == {"images": ["hello-world:latest"]}
+        assert loaded["data"] == b"tar-bytes"
+
+    @pytest.mark.asyncio
+    async def test_upload_writes_off_event_loop(self, mock_manager):
+        """一時ファイルへの書き込みはスレッドプールで行い、イベントループを塞がない"""
+        from starlette.concurrency import run_in_threadpool
+        from services.orchestrator.main import app
+
+        offloaded = []
+
+        async def recording_threadpool(func, *args, **kwargs):
+            offloaded.append(getattr(func, "__name__", repr(func)))
+            return await run_in_threadpool(func, *args, **kwargs)
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            with patch("services.orchestrator.main.run_in_threadpool", recording_threadpool):
+                async with AsyncClient(transport=transport, base_url="http://test") as client:
+                    response = await client.post("/images/load", content=b"tar-bytes")
+
+        assert response.status_code == 200
+        assert {"write", "flush", "close"} <= set(offloaded)
+
+    @pytest.mark.asyncio
+    async def test_upload_over_limit_rejected(self, mock_manager):
+        """IMAGE_ARCHIVE_MAX_BYTES を超えるアップロードは 413 で拒否する"""
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            with patch("services.orchestrator.main.config.IMAGE_ARCHIVE_MAX_BYTES", 4):
+                async with AsyncClient(transport=transport, base_url="http://test") as client:
+                    response = await client.post("/images/load", content=b"tar-bytes")
+
+        assert response.status_code == 413
+        mock_manager.load_image_archive.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_load_empty_upload_rejected(self, mock_manager):
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            async with AsyncClient(transport=transport, base_url="http://test") as client:
+                response = await client.post("/images/load", content=b"")
+
+        assert response.status_code == 400
+        mock_manager.load_image_archive.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_load_archive_by_path(self, mock_manager, tmp_path):
+        """IMAGE_ARCHIVE_DIR 配下のファイルをパス指定で読み込む"""
+        from services.orchestrator.main import app
+
+        archive = tmp_path / "hello.tar"
+        archive.write_bytes(b"tar-bytes")
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            with patch("services.orchestrator.main.config.IMAGE_ARCHIVE_DIR", str(tmp_path)):
+                async with AsyncClient(transport=transport, base_url="http://test") as client:
+                    response = await client.post("/images/load", params={"path": "hello.tar"})
+
+        assert response.status_code == 200
+        mock_manager.load_image_archive.assert_awaited_once_with(str(archive.resolve()))
+
+    @pytest.mark.asyncio
+    async def test_load_archive_path_traversal_rejected(self, mock_manager, tmp_path):
+        """IMAGE_ARCHIVE_DIR 外のパスは拒否する"""
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            with patch("services.orchestrator.main.config.IMAGE_ARCHIVE_DIR", str(tmp_path)):
+                async with AsyncClient(transport=transport, base_url="http://test") as client:
+                    response = await client.post(
+                        "/images/load", params={"path": "../../etc/passwd"}
+                    )
+
+        assert response.status_code == 400
+        mock_manager.load_image_archive.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_load_archive_path_not_found(self, mock_manager, tmp_path):
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            with patch("services.orchestrator.main.config.IMAGE_ARCHIVE_DIR", str(tmp_path)):
+                async with AsyncClient(transport=transport, base_url="http://test") as client:
+                    response = await client.post("/images/load", params={"path": "missing.tar"})
+
+        assert response.status_code == 404
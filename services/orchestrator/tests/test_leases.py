@@ -0,0 +1,68 @@
+"""
+Tests for worker leases (リースの発行・更新・期限切れ)
+"""
+
+import pytest
+
+from services.orchestrator.leases import LeaseNotFoundError, LeaseTable
+
+
+def test_grant_and_renew():
+    table = LeaseTable()
+    lease = table.grant("lambda-a", "a", ttl_seconds=30, now=100.0)
+
+    assert lease.expires_at == 130.0
+    assert table.is_leased("lambda-a", now=129.0)
+
+    renewed = table.renew(lease.lease_id, now=120.0)
+    assert renewed.expires_at == 150.0
+
+    renewed = table.renew(lease.lease_id, ttl_seconds=60, now=120.0)
+    assert renewed.expires_at == 180.0
+    assert renewed.ttl_seconds == 60
+
+
+def test_grant_same_container_extends_existing_lease():
+    table = LeaseTable()
+    first = table.grant("lambda-a", "a", ttl_seconds=30, now=100.0)
+    second = table.grant("lambda-a", "a", ttl_seconds=30, now=110.0)
+
+    assert second.lease_id == first.lease_id
+    assert second.expires_at == 140.0
+    assert len(table.snapshot()) == 1
+
+
+def test_expired_lease_cannot_be_renewed():
+    table = LeaseTable()
+    lease = table.grant("lambda-a", "a", ttl_seconds=30, now=100.0)
+
+    assert not table.is_leased("lambda-a", now=130.0)
+    assert table.expired(now=130.0) == [lease]
+    with pytest.raises(LeaseNotFoundError):
+        table.renew(lease.lease_id, now=131.0)
+
+
+def test_grant_replaces_expired_lease():
+    """期限切れ (回収前) のリースがあるコンテナには新しいリースを発行する"""
+    table = LeaseTable()
+    expired = table.grant("lambda-a", "a", ttl_seconds=30, now=0.0)
+
+    lease = table.grant("lambda-a", "a", ttl_seconds=60, now=100.0)
+
+    assert lease.lease_id != expired.lease_id
+    assert lease.expires_at == 160.0
+    assert table.is_leased("lambda-a", now=100.0)
+    assert [item["lease_id"] for item in table.snapshot()] == [lease.lease_id]
+
+
+def test_release():
+    table = LeaseTable()
+    lease = table.grant("lambda-a", "a", ttl_seconds=30, now=100.0)
+
+    assert table.release(lease.lease_id) is lease
+    assert table.release(lease.lease_id) is None
+    assert not table.is_leased("lambda-a", now=100.0)
+
+    table.grant("lambda-b", "b", ttl_seconds=30, now=100.0)
+    table.release_container("lambda-b")
+    assert table.snapshot() == []
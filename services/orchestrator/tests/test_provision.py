@@ -169,3 +169,129 @@ class TestHeartbeatEndpoint:
                 )
 
         assert response.status_code == 200
+
+
+class TestStopEndpoint:
+    """Tests for POST /containers/{id}/stop endpoint"""
+
+    @pytest.fixture
+    def mock_manager(self):
+        manager = MagicMock()
+        manager.stop_container = AsyncMock()
+        return manager
+
+    @pytest.mark.asyncio
+    async def test_stop_with_grace_period(self, mock_manager):
+        """猶予期間を指定して停止し、コンテナは削除しない"""
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            async with AsyncClient(transport=transport, base_url="http://test") as client:
+                response = await client.post(
+                    "/containers/lambda-hello-1234/stop", params={"grace_period": 20}
+                )
+
+        assert response.status_code == 200
+        assert response.json() == {"status": "stopped"}
+        mock_manager.stop_container.assert_called_once_with(
+            "lambda-hello-1234", grace_period=20, remove=False
+        )
+
+    @pytest.mark.asyncio
+    async def test_stop_rejects_negative_grace_period(self, mock_manager):
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            async with AsyncClient(transport=transport, base_url="http://test") as client:
+                response = await client.post(
+                    "/containers/lambda-hello-1234/stop", params={"grace_period": -1}
+                )
+
+        assert response.status_code == 422
+        mock_manager.stop_container.assert_not_called()
+
+
+class TestIdleSimulationEndpoint:
+    """Tests for GET /containers/idle endpoint"""
+
+    @pytest.fixture
+    def mock_manager(self):
+        manager = MagicMock()
+        manager.simulate_idle_cleanup = AsyncMock(
+            return_value=[
+                {"name": "lambda-a", "idle_seconds": 120, "status": "running", "action": "stop"}
+            ]
+        )
+        return manager
+
+    @pytest.mark.asyncio
+    async def test_simulate_with_custom_timeout(self, mock_manager):
+        """指定したタイムアウトで評価し、停止対象を返す"""
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            async with AsyncClient(transport=transport, base_url="http://test") as client:
+                response = await client.get("/containers/idle", params={"timeout_minutes": 1})
+
+        assert response.status_code == 200
+        data = response.json()
+        assert data["dry_run"] is True
+        assert data["timeout_seconds"] == 60
+        assert data["containers"][0]["action"] == "stop"
+        mock_manager.simulate_idle_cleanup.assert_called_once_with(60)
+
+
+class TestFunctionSyncEndpoint:
+    """Tests for POST /functions/sync endpoint"""
+
+    @pytest.fixture
+    def mock_manager(self):
+        plan = [{"action": "pull", "function": "a", "target": "a:latest", "platform": None}]
+
+        async def apply(steps):
+            for index, step in enumerate(steps, start=1):
+                yield {**step, "index": index, "total": len(steps), "status": "done"}
+
+        manager = MagicMock()
+        manager.plan_function_sync = AsyncMock(return_value=plan)
+        manager.apply_function_sync = apply
+        return manager
+
+    @pytest.mark.asyncio
+    async def test_sync_streams_plan_progress_and_summary(self, mock_manager):
+        import json
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            async with AsyncClient(transport=transport, base_url="http://test") as client:
+                response = await client.post(
+                    "/functions/sync", json={"functions": [{"name": "a"}], "prune": True}
+                )
+
+        assert response.status_code == 200
+        events = [json.loads(line) for line in response.text.splitlines()]
+        assert [e["event"] for e in events] == ["plan", "progress", "complete"]
+        assert events[-1] == {"event": "complete", "done": 1, "failed": 0}
+        args, kwargs = mock_manager.plan_function_sync.call_args
+        assert args[0][0].name == "a"
+        assert kwargs["prune"] is True
+
+    @pytest.mark.asyncio
+    async def test_sync_dry_run_returns_plan_only(self, mock_manager):
+        import json
+        from services.orchestrator.main import app
+
+        transport = ASGITransport(app=app)  # type: ignore
+        with patch("services.orchestrator.main.orchestrator", mock_manager):
+            async with AsyncClient(transport=transport, base_url="http://test") as client:
+                response = await client.post(
+                    "/functions/sync", json={"functions": [{"name": "a"}], "dry_run": True}
+                )
+
+        events = [json.loads(line) for line in response.text.splitlines()]
+        assert len(events) == 1
+        assert events[0]["dry_run"] is True
@@ -0,0 +1,91 @@
+"""
+Tests for ResourceMonitor (OOM 検出と推奨メモリ算出)
+"""
+
+from services.orchestrator.resource_monitor import (
+    OOM_KILLED,
+    ResourceMonitor,
+    cgroup_memory_limit,
+    extract_memory_usage,
+)
+
+MB = 1024 * 1024
+
+
+def test_extract_memory_usage_cgroup_v1():
+    """cgroup v1 では max_usage をピーク値として使用"""
+    stats = {"memory_stats": {"usage": 100 * MB, "max_usage": 150 * MB, "limit": 512 * MB}}
+    assert extract_memory_usage(stats) == (150 * MB, 512 * MB)
+
+
+def test_extract_memory_usage_cgroup_v2():
+    """cgroup v2 では usage を使用 (max_usage なし)"""
+    stats = {"memory_stats": {"usage": 80 * MB, "limit": 256 * MB}}
+    assert extract_memory_usage(stats) == (80 * MB, 256 * MB)
+
+
+def test_extract_memory_usage_empty():
+    assert extract_memory_usage({}) == (0, 0)
+
+
+def test_record_memory_keeps_peak():
+    monitor = ResourceMonitor()
+    monitor.record_memory("func", 200 * MB)
+    monitor.record_memory("func", 100 * MB)
+    assert monitor.functions["func"].peak_memory_bytes == 200 * MB
+
+
+def test_record_oom_marks_container_state():
+    monitor = ResourceMonitor()
+    monitor.record_oom("func", "lambda-func-1234")
+
+    assert monitor.functions["func"].oom_kills == 1
+    assert monitor.get_container_state("lambda-func-1234") == OOM_KILLED
+
+    monitor.clear_container_state("lambda-func-1234")
+    assert monitor.get_container_state("lambda-func-1234") is None
+
+
+def test_suggest_memory_without_samples():
+    monitor = ResourceMonitor()
+    assert monitor.suggest_memory("unknown") is None
+
+
+def test_suggest_memory_rounds_up_with_headroom():
+    """ピーク 100MB x 1.5 = 150MB -> 64MB 単位で切り上げて 192MB"""
+    monitor = ResourceMonitor(headroom=1.5)
+    monitor.record_memory("func", 100 * MB)
+    assert monitor.suggest_memory("func") == 192 * MB
+
+
+def test_suggest_memory_has_minimum():
+    monitor = ResourceMonitor(headroom=1.5)
+    monitor.record_memory("func", 10 * MB)
+    assert monitor.suggest_memory("func") == 128 * MB
+
+
+def test_suggest_memory_after_oom_exceeds_limit():
+    """OOM 発生時は現在の上限より大きい値を推奨"""
+    monitor = ResourceMonitor(headroom=1.5)
+    monitor.record_memory("func", 120 * MB, limit_bytes=128 * MB)
+    monitor.record_oom("func", "func")
+    assert monitor.suggest_memory("func") == 192 * MB
+
+    snapshot = monitor.snapshot()
+    assert snapshot["func"]["oom_kills"] == 1
+    assert snapshot["func"]["suggested_memory_mb"] == 192
+
+
+def test_cgroup_memory_limit():
+    """HostConfig.Memory が 0 / 未設定のコンテナは上限なし"""
+    assert cgroup_memory_limit({"HostConfig": {"Memory": 256 * MB}}) == 256 * MB
+    assert cgroup_memory_limit({"HostConfig": {"Memory": 0}}) == 0
+    assert cgroup_memory_limit({}) == 0
+
+
+def test_suggest_memory_after_oom_without_limit_uses_peak():
+    """上限が記録されていない場合、OOM 後もピーク使用量から算出する"""
+    monitor = ResourceMonitor(headroom=1.5)
+    monitor.record_memory("func", 200 * MB)
+    monitor.record_oom("func", "func")
+    assert monitor.suggest_memory("func") == 320 * MB
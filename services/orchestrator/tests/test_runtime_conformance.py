@@ -0,0 +1,11 @@
+"""
+Runtime conformance suite against the local Docker socket
+
+ESB_RUNTIME_TEST=1 の場合のみ実行されます (services/orchestrator/runtimetest を参照)。
+"""
+
+from services.orchestrator.runtimetest import RuntimeConformance
+
+
+class TestDockerRuntimeConformance(RuntimeConformance):
+    """DockerAdaptor (docker-py) の適合性"""
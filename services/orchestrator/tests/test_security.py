@@ -0,0 +1,45 @@
+"""
+Tests for container security options (実行ユーザーと capability)
+"""
+
+from unittest.mock import patch
+
+from services.common.models.internal import ContainerSecurity
+from services.orchestrator.security import build_security_options
+
+
+def test_default_drops_all_and_adds_allowlist():
+    with patch("services.orchestrator.security.config") as mock_config:
+        mock_config.CONTAINER_DEFAULT_USER = None
+        mock_config.CONTAINER_DEFAULT_CAPABILITIES = ["NET_BIND_SERVICE", "CHOWN"]
+        options = build_security_options()
+
+    assert options == {"cap_drop": ["ALL"], "cap_add": ["CHOWN", "NET_BIND_SERVICE"]}
+
+
+def test_function_settings_override_defaults():
+    """関数ごとの設定がデフォルトより優先され、capability 表記は正規化される"""
+    security = ContainerSecurity(
+        user="1000:1000", groups=["video"], capabilities=["cap_net_raw", "NET_RAW"]
+    )
+    with patch("services.orchestrator.security.config") as mock_config:
+        mock_config.CONTAINER_DEFAULT_USER = "nobody"
+        mock_config.CONTAINER_DEFAULT_CAPABILITIES = ["CHOWN"]
+        options = build_security_options(security)
+
+    assert options == {
+        "cap_drop": ["ALL"],
+        "cap_add": ["NET_RAW"],
+        "user": "1000:1000",
+        "group_add": ["video"],
+    }
+
+
+def test_empty_capabilities_grants_nothing():
+    with patch("services.orchestrator.security.config") as mock_config:
+        mock_config.CONTAINER_DEFAULT_USER = "nobody"
+        mock_config.CONTAINER_DEFAULT_CAPABILITIES = ["CHOWN"]
+        options = build_security_options(ContainerSecurity(capabilities=[]))
+
+    assert options["cap_add"] == []
+    assert options["user"] == "nobody"
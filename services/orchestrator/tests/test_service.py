@@ -1,7 +1,10 @@
+import asyncio
+import time
+
 import pytest
 from unittest.mock import MagicMock, AsyncMock, Mock, patch
 import docker.errors
-from services.orchestrator.service import ContainerOrchestrator
+from services.orchestrator.service import FUNCTION_LABEL, ContainerOrchestrator
 
 
 @pytest.fixture
@@ -232,3 +235,819 @@ async def test_ensure_container_running_handles_409_conflict(mock_docker_adaptor
         mock_docker_adaptor.run_container.assert_awaited_once()
         # 409後にget_containerが再度呼ばれた
         assert mock_docker_adaptor.get_container.await_count == 2
+
+
+@pytest.mark.asyncio
+async def test_ensure_container_running_with_platform(mock_docker_adaptor):
+    """platform 指定時はイメージを確保してから同じ platform でコンテナを作成する"""
+    mock_docker_adaptor.get_container = AsyncMock(side_effect=docker.errors.NotFound("Not found"))
+    mock_docker_adaptor.ensure_image = AsyncMock()
+    mock_docker_adaptor.run_container = AsyncMock()
+    mock_docker_adaptor.reload_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+
+    mock_container = MagicMock()
+    mock_container.attrs = {"NetworkSettings": {"Networks": {"test-net": {"IPAddress": "1.2.3.4"}}}}
+    mock_docker_adaptor.run_container.return_value = mock_container
+
+    with patch.object(manager, "_wait_for_readiness", new_callable=AsyncMock):
+        await manager.ensure_container_running("test-func", "test-image", platform="linux/arm64")
+
+    mock_docker_adaptor.ensure_image.assert_awaited_once_with("test-image", "linux/arm64")
+    assert mock_docker_adaptor.run_container.call_args.kwargs["platform"] == "linux/arm64"
+
+
+@pytest.mark.asyncio
+async def test_ensure_container_running_platform_mismatch_does_not_create(mock_docker_adaptor):
+    """イメージが要求プラットフォームに対応していなければコンテナを作成しない"""
+    from services.orchestrator.docker_adaptor import ImagePlatformError
+
+    mock_docker_adaptor.get_container = AsyncMock(side_effect=docker.errors.NotFound("Not found"))
+    mock_docker_adaptor.ensure_image = AsyncMock(
+        side_effect=ImagePlatformError("test-image", "linux/arm64")
+    )
+    mock_docker_adaptor.run_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+
+    with pytest.raises(ImagePlatformError):
+        await manager.ensure_container_running("test-func", "test-image", platform="linux/arm64")
+
+    mock_docker_adaptor.run_container.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_handle_docker_event_records_oom(mock_docker_adaptor):
+    """OOM イベント受信時に関数単位で記録される"""
+    manager = ContainerOrchestrator(network="test-net")
+
+    await manager.handle_docker_event(
+        {
+            "Type": "container",
+            "Action": "oom",
+            "Actor": {
+                "ID": "abc",
+                "Attributes": {
+                    "name": "lambda-hello-1234",
+                    "function_name": "hello",
+                    "created_by": "esb",
+                },
+            },
+        }
+    )
+    # OOM 以外のイベントは無視
+    await manager.handle_docker_event({"Action": "die", "Actor": {"Attributes": {}}})
+
+    stats = manager.get_resource_stats()
+    assert stats["hello"]["oom_kills"] == 1
+    assert manager.resource_monitor.get_container_state("lambda-hello-1234") == "OOM_KILLED"
+
+
+@pytest.mark.asyncio
+async def test_ensure_container_running_recreates_oom_killed(mock_docker_adaptor):
+    """OOM_RESTART_POLICY=recreate の場合、OOM Kill されたコンテナは再作成される"""
+    mock_docker_adaptor.get_container = AsyncMock()
+    mock_docker_adaptor.run_container = AsyncMock()
+    mock_docker_adaptor.reload_container = AsyncMock()
+    mock_docker_adaptor.remove_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+
+    oom_container = MagicMock()
+    oom_container.status = "exited"
+    oom_container.attrs = {"State": {"OOMKilled": True}}
+    mock_docker_adaptor.get_container.return_value = oom_container
+
+    new_container = MagicMock()
+    new_container.attrs = {"NetworkSettings": {"Networks": {"test-net": {"IPAddress": "1.2.3.4"}}}}
+    mock_docker_adaptor.run_container.return_value = new_container
+
+    with patch("services.orchestrator.service.config.OOM_RESTART_POLICY", "recreate"):
+        with patch.object(manager, "_wait_for_readiness", new_callable=AsyncMock):
+            await manager.ensure_container_running("test-func", "test-image")
+
+    mock_docker_adaptor.remove_container.assert_awaited_once_with(oom_container, force=True)
+    mock_docker_adaptor.run_container.assert_awaited_once()
+    assert manager.get_resource_stats()["test-func"]["oom_kills"] == 1
+
+
+@pytest.mark.asyncio
+async def test_stop_container_without_remove(mock_docker_adaptor):
+    """remove=False の場合は猶予期間付きで停止のみ行い、削除しない"""
+    container = MagicMock()
+    mock_docker_adaptor.get_container = AsyncMock(return_value=container)
+    mock_docker_adaptor.stop_container = AsyncMock()
+    mock_docker_adaptor.remove_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    await manager.stop_container("lambda-hello-1234", grace_period=30, remove=False)
+
+    mock_docker_adaptor.stop_container.assert_awaited_once_with(container, timeout=30)
+    mock_docker_adaptor.remove_container.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_stop_container_not_found_propagates(mock_docker_adaptor):
+    """存在しないコンテナの停止は NotFound を送出し、管理情報は破棄する"""
+    mock_docker_adaptor.get_container = AsyncMock(side_effect=docker.errors.NotFound("Not found"))
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.last_accessed["lambda-gone"] = time.time()
+
+    with pytest.raises(docker.errors.NotFound):
+        await manager.stop_container("lambda-gone", remove=False)
+
+    assert "lambda-gone" not in manager.last_accessed
+
+
+@pytest.mark.asyncio
+async def test_stop_container_api_error_propagates(mock_docker_adaptor):
+    """停止に失敗した場合はエラーを送出し、コンテナを管理対象に残す"""
+    mock_docker_adaptor.get_container = AsyncMock(return_value=MagicMock())
+    mock_docker_adaptor.stop_container = AsyncMock(
+        side_effect=docker.errors.APIError("boom", response=MagicMock(status_code=500))
+    )
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.last_accessed["lambda-stuck"] = time.time()
+
+    with pytest.raises(docker.errors.APIError):
+        await manager.stop_container("lambda-stuck", remove=False)
+
+    assert "lambda-stuck" in manager.last_accessed
+
+
+@pytest.mark.asyncio
+async def test_handle_docker_event_covers_adopted_containers_only(mock_docker_adaptor):
+    """取り込んだ外部コンテナの OOM は記録し、管理外のコンテナのイベントは無視する"""
+    manager = ContainerOrchestrator(network="test-net")
+    manager.adopted.add("lambda-echo-manual1")
+
+    def oom(name):
+        return {
+            "Action": "oom",
+            "Actor": {"Attributes": {"name": name, "function_name": "lambda-echo"}},
+        }
+
+    await manager.handle_docker_event(oom("lambda-echo-manual1"))
+    await manager.handle_docker_event(oom("unrelated"))
+
+    assert manager.get_resource_stats()["lambda-echo"]["oom_kills"] == 1
+    assert manager.resource_monitor.get_container_state("unrelated") is None
+
+
+@pytest.mark.asyncio
+async def test_ensure_container_running_fails_fast_when_quarantined(mock_docker_adaptor):
+    """異常終了を繰り返す関数は隔離され、コンテナを操作せずに失敗する"""
+    from services.orchestrator.crash_loop import FunctionQuarantinedError
+
+    mock_docker_adaptor.get_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    for _ in range(manager.crash_loop.threshold):
+        await manager.handle_docker_event(
+            {
+                "Action": "die",
+                "Actor": {
+                    "Attributes": {"name": "hello", "exitCode": "1", "created_by": "esb"}
+                },
+            }
+        )
+
+    with pytest.raises(FunctionQuarantinedError):
+        await manager.ensure_container_running("hello")
+    mock_docker_adaptor.get_container.assert_not_awaited()
+
+    assert manager.clear_quarantine("hello") is True
+    assert manager.get_quarantined_functions() == {}
+
+
+@pytest.mark.asyncio
+async def test_handle_docker_event_ignores_graceful_stop(mock_docker_adaptor):
+    """SIGTERM による停止 (終了コード 143) はクラッシュとして数えない"""
+    manager = ContainerOrchestrator(network="test-net")
+    for _ in range(manager.crash_loop.threshold):
+        await manager.handle_docker_event(
+            {
+                "Action": "die",
+                "Actor": {
+                    "Attributes": {"name": "hello", "exitCode": "143", "created_by": "esb"}
+                },
+            }
+        )
+
+    assert manager.get_quarantined_functions() == {}
+
+
+@pytest.mark.asyncio
+async def test_simulate_idle_cleanup_does_not_stop(mock_docker_adaptor):
+    """ドライランは停止対象を返すのみで、コンテナの停止や管理情報の削除は行わない"""
+    running = MagicMock()
+    running.status = "running"
+
+    async def get_container(name):
+        if name == "lambda-gone":
+            raise docker.errors.NotFound("gone")
+        return running
+
+    mock_docker_adaptor.get_container = AsyncMock(side_effect=get_container)
+    mock_docker_adaptor.stop_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    now = time.time()
+    manager.last_accessed = {
+        "lambda-idle": now - 600,
+        "lambda-gone": now - 1200,
+        "lambda-active": now - 10,
+    }
+
+    candidates = await manager.simulate_idle_cleanup(timeout_seconds=300)
+
+    assert [c["name"] for c in candidates] == ["lambda-gone", "lambda-idle"]
+    assert candidates[0]["action"] == "untrack"
+    assert candidates[1]["action"] == "stop"
+    mock_docker_adaptor.stop_container.assert_not_awaited()
+    assert len(manager.last_accessed) == 3
+
+
+def _running_containers(*names):
+    containers = []
+    for name in names:
+        container = MagicMock()
+        container.id = f"id-{name}"
+        container.name = name
+        containers.append(container)
+    return containers
+
+
+@pytest.mark.asyncio
+async def test_provision_containers_rejected_by_admission(mock_docker_adaptor):
+    """ホスト容量を超えるプロビジョニングはコンテナを作成せずに拒否される"""
+    from services.orchestrator.admission import (
+        AdmissionController,
+        AdmissionRejectedError,
+        HostCapacity,
+    )
+
+    mock_docker_adaptor.run_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.admission = AdmissionController(
+        HostCapacity(memory_bytes=512 * 1024 * 1024, cpus=4),
+        memory_per_worker=128 * 1024 * 1024,
+        cpus_per_worker=0.25,
+    )
+    mock_docker_adaptor.list_containers = AsyncMock(
+        return_value=_running_containers("lambda-a", "lambda-b", "lambda-c")
+    )
+
+    with pytest.raises(AdmissionRejectedError) as exc_info:
+        await manager.provision_containers("hello", count=2)
+
+    assert exc_info.value.shortfall == {"memory_bytes": 128 * 1024 * 1024}
+    mock_docker_adaptor.run_container.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_ensure_container_running_mounts_function_volume(mock_docker_adaptor):
+    """永続ボリュームを作成 (既存なら再利用) し、指定パスにマウントする"""
+    from services.common.models.internal import FunctionVolume
+
+    mock_docker_adaptor.get_container = AsyncMock(side_effect=docker.errors.NotFound("Not found"))
+    mock_docker_adaptor.ensure_volume = AsyncMock()
+    mock_docker_adaptor.reload_container = AsyncMock()
+    mock_container = MagicMock()
+    mock_container.attrs = {"NetworkSettings": {"Networks": {"test-net": {"IPAddress": "1.2.3.4"}}}}
+    mock_docker_adaptor.run_container = AsyncMock(return_value=mock_container)
+
+    manager = ContainerOrchestrator(network="test-net")
+    volume = FunctionVolume(path="/mnt/cache", size_mb=256)
+    with patch.object(manager, "_wait_for_readiness", new_callable=AsyncMock):
+        await manager.ensure_container_running("hello", "hello:latest", volume=volume)
+
+    mock_docker_adaptor.ensure_volume.assert_awaited_once()
+    assert mock_docker_adaptor.ensure_volume.call_args.args[0] == "esb-data-hello"
+    assert mock_docker_adaptor.run_container.call_args.kwargs["volumes"] == {
+        "esb-data-hello": {"bind": "/mnt/cache", "mode": "rw"}
+    }
+
+
+@pytest.mark.asyncio
+async def test_prepare_volume_binds_host_root(mock_docker_adaptor, tmp_path):
+    """ディレクトリは VOLUME_ROOT に作成し、Docker には VOLUME_HOST_ROOT のパスを渡す"""
+    from services.common.models.internal import FunctionVolume
+
+    mock_docker_adaptor.ensure_volume = AsyncMock()
+    manager = ContainerOrchestrator(network="test-net")
+
+    with (
+        patch("services.orchestrator.service.config.VOLUME_ROOT", str(tmp_path)),
+        patch("services.orchestrator.service.config.VOLUME_HOST_ROOT", "/host/functions"),
+    ):
+        await manager._prepare_volume("team/hello", FunctionVolume(path="/mnt/cache"))
+
+    assert (tmp_path / "team_hello").is_dir()
+    assert mock_docker_adaptor.ensure_volume.call_args.kwargs["driver_opts"] == {
+        "type": "none",
+        "o": "bind",
+        "device": "/host/functions/team_hello",
+    }
+
+
+@pytest.mark.asyncio
+async def test_ensure_container_running_blocked_by_volume_quota(mock_docker_adaptor):
+    """ボリューム使用量がクォータを超えている関数はコンテナを起動しない"""
+    from services.orchestrator.volumes import VolumeQuotaExceededError
+
+    mock_docker_adaptor.get_container = AsyncMock()
+    mock_docker_adaptor.get_volume_usage = AsyncMock(return_value={"hello": 300 * 1024 * 1024})
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.volume_quota.set_quota("hello", 256)
+    await manager.collect_volume_usage()
+
+    with pytest.raises(VolumeQuotaExceededError):
+        await manager.ensure_container_running("hello")
+    mock_docker_adaptor.get_container.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_ensure_container_running_applies_security_options(mock_docker_adaptor):
+    """関数ごとのユーザー・capability 設定がコンテナ作成に反映される"""
+    from services.common.models.internal import ContainerSecurity
+
+    mock_docker_adaptor.get_container = AsyncMock(side_effect=docker.errors.NotFound("Not found"))
+    mock_docker_adaptor.reload_container = AsyncMock()
+    mock_container = MagicMock()
+    mock_container.attrs = {"NetworkSettings": {"Networks": {"test-net": {"IPAddress": "1.2.3.4"}}}}
+    mock_docker_adaptor.run_container = AsyncMock(return_value=mock_container)
+
+    manager = ContainerOrchestrator(network="test-net")
+    security = ContainerSecurity(user="993:990", capabilities=["NET_BIND_SERVICE"])
+    with patch.object(manager, "_wait_for_readiness", new_callable=AsyncMock):
+        await manager.ensure_container_running("hello", "hello:latest", security=security)
+
+    kwargs = mock_docker_adaptor.run_container.call_args.kwargs
+    assert kwargs["user"] == "993:990"
+    assert kwargs["cap_drop"] == ["ALL"]
+    assert kwargs["cap_add"] == ["NET_BIND_SERVICE"]
+
+
+@pytest.mark.asyncio
+async def test_ensure_container_rejected_by_admission_is_not_tracked(mock_docker_adaptor):
+    """アドミッションで拒否された要求はアクセス記録に残らず、容量を消費しない"""
+    from services.orchestrator.admission import (
+        AdmissionController,
+        AdmissionRejectedError,
+        HostCapacity,
+    )
+
+    mock_docker_adaptor.get_container = AsyncMock(side_effect=docker.errors.NotFound("Not found"))
+    mock_docker_adaptor.run_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.admission = AdmissionController(
+        HostCapacity(memory_bytes=256 * 1024 * 1024, cpus=4),
+        memory_per_worker=128 * 1024 * 1024,
+        cpus_per_worker=0.25,
+    )
+    mock_docker_adaptor.list_containers = AsyncMock(
+        return_value=_running_containers("lambda-a", "lambda-b")
+    )
+
+    for _ in range(3):
+        with pytest.raises(AdmissionRejectedError):
+            await manager.ensure_container_running("lambda-c")
+
+    assert "lambda-c" not in manager.last_accessed
+    mock_docker_adaptor.run_container.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_admission_counts_only_running_containers(mock_docker_adaptor):
+    """Heartbeat で記録された名前や終了済みのコンテナは稼働中ワーカーとして計上しない"""
+    from services.orchestrator.admission import AdmissionController, HostCapacity
+
+    mock_docker_adaptor.list_containers = AsyncMock(return_value=_running_containers("lambda-a"))
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.admission = AdmissionController(
+        HostCapacity(memory_bytes=256 * 1024 * 1024, cpus=4),
+        memory_per_worker=128 * 1024 * 1024,
+        cpus_per_worker=0.25,
+    )
+    manager.last_accessed = {"lambda-a": 0.0, "lambda-exited": 0.0, "lambda-heartbeat": 0.0}
+
+    await manager._admit("lambda-b")
+
+    filters = mock_docker_adaptor.list_containers.call_args.kwargs["filters"]
+    assert filters["status"] == "running"
+
+
+def _sync_container(name, function_name, image, image_id="sha256:old"):
+    container = MagicMock()
+    container.name = name
+    container.labels = {FUNCTION_LABEL: function_name} if function_name else {}
+    container.attrs = {"Config": {"Image": image}, "Image": image_id}
+    return container
+
+
+@pytest.mark.asyncio
+async def test_plan_function_sync(mock_docker_adaptor):
+    """イメージが変わった関数は update、一覧にない関数は prune 時のみ delete"""
+    from services.common.models.internal import FunctionSpec
+
+    mock_docker_adaptor.list_containers = AsyncMock(
+        return_value=[
+            _sync_container("lambda-a-1", "a", "a:v1"),
+            _sync_container("lambda-b-1", "b", "b:latest"),
+            # 同じタグでもローカルのイメージ ID と異なれば update
+            _sync_container("lambda-c-1", "c", "c:latest"),
+            _sync_container("lambda-old-1", "old", "old:latest"),
+            # 関数名ラベルのないコンテナは関数を特定できないため削除しない
+            _sync_container("lambda-unlabeled", None, "unlabeled:latest"),
+        ]
+    )
+    image_ids = {"a:v2": None, "b:latest": "sha256:old", "c:latest": "sha256:new"}
+    mock_docker_adaptor.get_image_id = AsyncMock(side_effect=image_ids.get)
+    manager = ContainerOrchestrator(network="test-net")
+    specs = [FunctionSpec(name="a", image="a:v2"), FunctionSpec(name="b"), FunctionSpec(name="c")]
+
+    plan = await manager.plan_function_sync(specs)
+    assert [(s["action"], s["target"]) for s in plan] == [
+        ("pull", "a:v2"),
+        ("pull", "b:latest"),
+        ("pull", "c:latest"),
+        ("update", "lambda-a-1"),
+        ("update", "lambda-c-1"),
+    ]
+    assert plan[2]["image_id"] == "sha256:new"
+
+    plan = await manager.plan_function_sync(specs, prune=True)
+    deletes = [s["target"] for s in plan if s["action"] == "delete"]
+    assert deletes == ["lambda-old-1"]
+
+
+@pytest.mark.asyncio
+async def test_apply_function_sync_reports_progress(mock_docker_adaptor):
+    """操作ごとに進捗を返し、失敗しても残りの操作を継続する"""
+    mock_docker_adaptor.ensure_image = AsyncMock(
+        side_effect=[Exception("pull failed"), MagicMock(id="sha256:b")]
+    )
+    mock_docker_adaptor.get_container = AsyncMock(return_value=MagicMock())
+    mock_docker_adaptor.stop_container = AsyncMock()
+    mock_docker_adaptor.remove_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.last_accessed["lambda-a-1"] = 1.0
+    manager.locks["lambda-a-1"] = asyncio.Lock()
+    manager.adopted.add("lambda-a-1")
+    manager.grant_lease("lambda-a-1", "a", ttl_seconds=60)
+    plan = [
+        {"action": "pull", "function": "a", "target": "a:v2", "platform": None},
+        {
+            "action": "pull",
+            "function": "b",
+            "target": "b:latest",
+            "platform": None,
+            "image_id": "sha256:b",
+        },
+        {"action": "update", "function": "a", "target": "lambda-a-1"},
+    ]
+
+    events = [event async for event in manager.apply_function_sync(plan)]
+    mock_docker_adaptor.ensure_image.assert_awaited_with("b:latest", None, pull=True)
+
+    assert [e["status"] for e in events] == ["failed", "done", "done"]
+    assert events[0]["error"] == "pull failed"
+    assert events[2]["index"] == 3 and events[2]["total"] == 3
+    mock_docker_adaptor.remove_container.assert_awaited_once()
+    # 削除したコンテナの管理情報も破棄される
+    assert "lambda-a-1" not in manager.last_accessed
+    assert "lambda-a-1" not in manager.locks
+    assert "lambda-a-1" not in manager.adopted
+    assert manager.leases.snapshot() == []
+
+
+@pytest.mark.asyncio
+async def test_apply_function_sync_updates_containers_of_retagged_image(mock_docker_adaptor):
+    """pull で同じタグのイメージが更新された場合、古いイメージのコンテナを update する"""
+    mock_docker_adaptor.ensure_image = AsyncMock(return_value=MagicMock(id="sha256:new"))
+    mock_docker_adaptor.list_containers = AsyncMock(
+        return_value=[
+            _sync_container("lambda-b-1", "b", "b:latest", image_id="sha256:old"),
+            _sync_container("lambda-b-2", "b", "b:latest", image_id="sha256:new"),
+            _sync_container("lambda-c-1", "c", "c:latest", image_id="sha256:old"),
+        ]
+    )
+    container = MagicMock()
+    mock_docker_adaptor.get_container = AsyncMock(return_value=container)
+    mock_docker_adaptor.stop_container = AsyncMock()
+    mock_docker_adaptor.remove_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    plan = [
+        {
+            "action": "pull",
+            "function": "b",
+            "target": "b:latest",
+            "platform": None,
+            "image_id": "sha256:old",
+        }
+    ]
+
+    events = [event async for event in manager.apply_function_sync(plan)]
+
+    assert [(e["action"], e["target"], e["status"]) for e in events] == [
+        ("pull", "b:latest", "done"),
+        ("update", "lambda-b-1", "done"),
+    ]
+    assert events[-1]["index"] == 2 and events[-1]["total"] == 2
+    mock_docker_adaptor.get_container.assert_awaited_once_with("lambda-b-1")
+
+
+@pytest.mark.asyncio
+async def test_apply_function_sync_removes_under_container_lock(mock_docker_adaptor):
+    """update / delete は ensure_container_running と同じコンテナのロックを取得して行う"""
+    mock_docker_adaptor.get_container = AsyncMock(return_value=MagicMock())
+    mock_docker_adaptor.stop_container = AsyncMock()
+    mock_docker_adaptor.remove_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    lock = manager.locks.setdefault("lambda-a-1", asyncio.Lock())
+    plan = [{"action": "delete", "function": "a", "target": "lambda-a-1"}]
+
+    async with lock:
+        task = asyncio.create_task(anext(aiter(manager.apply_function_sync(plan))))
+        await asyncio.sleep(0)
+        mock_docker_adaptor.get_container.assert_not_awaited()
+
+    event = await task
+    assert event["status"] == "done"
+    mock_docker_adaptor.remove_container.assert_awaited_once()
+
+
+@pytest.mark.asyncio
+async def test_reclaim_expired_leases(mock_docker_adaptor):
+    """期限切れリースのコンテナは停止・削除され、リース中のコンテナはアイドル停止の対象外"""
+    mock_docker_adaptor.get_container = AsyncMock(return_value=MagicMock())
+    mock_docker_adaptor.stop_container = AsyncMock()
+    mock_docker_adaptor.remove_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.last_accessed = {"lambda-a": 0.0, "lambda-b": 0.0}
+    manager.leases.grant("lambda-a", "a", ttl_seconds=30, now=0.0)
+    manager.grant_lease("lambda-b", "b", ttl_seconds=3600)
+
+    assert list(manager._find_idle_containers(60, now=120.0)) == ["lambda-a"]
+
+    with patch("services.orchestrator.leases.time.time", return_value=120.0):
+        await manager.reclaim_expired_leases()
+
+    mock_docker_adaptor.get_container.assert_awaited_once_with("lambda-a")
+    mock_docker_adaptor.remove_container.assert_awaited_once()
+    assert "lambda-a" not in manager.last_accessed
+    assert "lambda-b" in manager.last_accessed
+    assert [lease["container_name"] for lease in manager.leases.snapshot()] == ["lambda-b"]
+
+
+@pytest.mark.asyncio
+async def test_reclaim_skips_lease_renewed_while_waiting_for_lock(mock_docker_adaptor):
+    """コンテナのロック待ちの間に更新されたリースは回収しない"""
+    mock_docker_adaptor.get_container = AsyncMock(return_value=MagicMock())
+    mock_docker_adaptor.stop_container = AsyncMock()
+    mock_docker_adaptor.remove_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    lease = manager.leases.grant("lambda-a", "a", ttl_seconds=30, now=0.0)
+    lock = manager.locks.setdefault("lambda-a", asyncio.Lock())
+
+    async with lock:
+        reclaim = asyncio.create_task(manager.reclaim_expired_leases())
+        await asyncio.sleep(0)
+        # ensure_container_running の処理中にリースが再発行される
+        manager.grant_lease("lambda-a", "a", ttl_seconds=60)
+    await reclaim
+
+    mock_docker_adaptor.stop_container.assert_not_awaited()
+    assert manager.leases.is_leased("lambda-a")
+    assert lease.lease_id not in [item["lease_id"] for item in manager.leases.snapshot()]
+
+
+@pytest.mark.asyncio
+async def test_simulate_idle_cleanup_includes_expired_leases(mock_docker_adaptor):
+    """ドライランは期限切れリースのコンテナを reclaim として含める"""
+    running = MagicMock()
+    running.status = "running"
+    mock_docker_adaptor.get_container = AsyncMock(return_value=running)
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.last_accessed = {"lambda-a": time.time() - 10}
+    manager.leases.grant("lambda-a", "a", ttl_seconds=30, now=0.0)
+
+    candidates = await manager.simulate_idle_cleanup(timeout_seconds=300)
+
+    assert [(c["name"], c["action"]) for c in candidates] == [("lambda-a", "reclaim")]
+
+
+@pytest.mark.asyncio
+async def test_admission_evicts_cheapest_idle_container(mock_docker_adaptor):
+    """容量不足時は再起動コストの低いアイドルコンテナを停止して受け入れる"""
+    from services.orchestrator.admission import AdmissionController, HostCapacity
+
+    def get_container(name):
+        container = MagicMock()
+        container.name = name
+        container.status = "running"
+        container.labels = {}
+        return container
+
+    running = {"slow", "fast"}
+
+    async def stop_container(container):
+        running.discard(container.name)
+
+    mock_docker_adaptor.get_container = AsyncMock(side_effect=get_container)
+    mock_docker_adaptor.stop_container = AsyncMock(side_effect=stop_container)
+    mock_docker_adaptor.list_containers = AsyncMock(
+        side_effect=lambda **kwargs: _running_containers(*sorted(running))
+    )
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.admission = AdmissionController(
+        HostCapacity(memory_bytes=256 * 1024 * 1024, cpus=4),
+        memory_per_worker=128 * 1024 * 1024,
+        cpus_per_worker=0.25,
+    )
+    manager.last_accessed = {"slow": 0.0, "fast": 0.0}
+    manager.profiles.record_cold_start("slow", 10.0)
+    manager.profiles.record_cold_start("fast", 0.2)
+
+    with patch("services.orchestrator.service.config") as mock_config:
+        mock_config.EVICTION_ENABLED = True
+        mock_config.EVICTION_MIN_IDLE_SECONDS = 30
+        mock_config.EVICTION_COLD_START_WEIGHT = 1.0
+        mock_config.EVICTION_IMAGE_WEIGHT = 0.5
+        mock_config.WORKER_MEMORY_RESERVATION_MB = 128
+        await manager._admit_or_evict("new-func")
+
+    mock_docker_adaptor.stop_container.assert_awaited_once()
+    assert list(manager.last_accessed) == ["slow"]
+
+
+@pytest.mark.asyncio
+async def test_evict_skips_busy_or_recently_used_container(mock_docker_adaptor):
+    """ロック中、または候補の選定後にアクセスされたコンテナは停止しない"""
+    container = MagicMock()
+    container.status = "running"
+    mock_docker_adaptor.get_container = AsyncMock(return_value=container)
+    mock_docker_adaptor.stop_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    manager.last_accessed = {"busy": 0.0, "fresh": time.time(), "idle": 0.0}
+    manager.locks["busy"] = asyncio.Lock()
+
+    async with manager.locks["busy"]:
+        assert await manager._evict("busy") is False
+    assert await manager._evict("fresh") is False
+    assert await manager._evict("idle") is True
+
+    mock_docker_adaptor.stop_container.assert_awaited_once_with(container)
+    assert set(manager.last_accessed) == {"busy", "fresh"}
+    assert "idle" not in manager.locks
+
+
+@pytest.mark.asyncio
+async def test_reload_function_code_restarts_matching_containers(mock_docker_adaptor):
+    """変更されたコードディレクトリに対応する関数のコンテナのみ再起動する"""
+
+    def container(name, function_name):
+        c = MagicMock()
+        c.name = name
+        c.labels = {"function_name": function_name}
+        return c
+
+    echo = container("lambda-echo", "lambda-echo")
+    s3 = container("lambda-s3", "lambda-s3")
+    mock_docker_adaptor.list_containers = AsyncMock(return_value=[echo, s3])
+    mock_docker_adaptor.restart_container = AsyncMock()
+
+    manager = ContainerOrchestrator(network="test-net")
+    await manager.reload_function_code("echo")
+
+    mock_docker_adaptor.restart_container.assert_awaited_once_with(echo, timeout=0)
+
+
+def test_dev_code_volume(tmp_path):
+    (tmp_path / "echo").mkdir()
+    with patch("services.orchestrator.service.config") as mock_config:
+        mock_config.DEV_CODE_ROOT = str(tmp_path)
+        mock_config.DEV_CODE_HOST_ROOT = "/home/dev/functions"
+        mock_config.DEV_CODE_MOUNT_PATH = "/var/task"
+
+        assert ContainerOrchestrator._dev_code_volume("lambda-echo") == {
+            "/home/dev/functions/echo": {"bind": "/var/task", "mode": "ro"}
+        }
+        assert ContainerOrchestrator._dev_code_volume("lambda-other") == {}
+
+        mock_config.DEV_CODE_ROOT = None
+        assert ContainerOrchestrator._dev_code_volume("lambda-echo") == {}
+
+
+@pytest.mark.asyncio
+async def test_adopt_external_containers(mock_docker_adaptor):
+    """採用ラベルを持つ外部コンテナをアクセス記録に登録し、一覧にも含める"""
+
+    def container(container_id, name):
+        c = MagicMock()
+        c.id = container_id
+        c.name = name
+        c.status = "running"
+        c.attrs = {}
+        return c
+
+    own = container("c1", "lambda-echo-1a2b3c4d")
+    external = container("c2", "lambda-echo-manual1")
+
+    async def list_containers(all=False, filters=None):
+        return [own] if filters["label"] == "created_by=esb" else [external]
+
+    mock_docker_adaptor.list_containers = AsyncMock(side_effect=list_containers)
+    manager = ContainerOrchestrator(network="test-net")
+
+    with patch("services.orchestrator.service.config") as mock_config:
+        mock_config.EXTERNAL_ADOPTION_ENABLED = True
+        mock_config.EXTERNAL_ADOPTION_LABEL = "esb.adopt=true"
+        mock_config.LAMBDA_PORT = 8080
+
+        assert await manager.adopt_external_containers() == ["lambda-echo-manual1"]
+        # 取り込み済みのコンテナは再度取り込まない
+        assert await manager.adopt_external_containers() == []
+        workers = await manager.list_managed_containers()
+
+    assert "lambda-echo-manual1" in manager.last_accessed
+    assert manager.adopted == {"lambda-echo-manual1"}
+    assert sorted(w.id for w in workers) == ["c1", "c2"]
+    assert {w.name: w.adopted for w in workers} == {
+        "lambda-echo-1a2b3c4d": False,
+        "lambda-echo-manual1": True,
+    }
+
+    # アイドル停止の対象になり、停止後は取り込み状態も解除される
+    manager.last_accessed["lambda-echo-manual1"] = 0
+    mock_docker_adaptor.get_container = AsyncMock(return_value=external)
+    mock_docker_adaptor.stop_container = AsyncMock()
+    await manager.stop_idle_containers(timeout_seconds=60)
+
+    mock_docker_adaptor.stop_container.assert_awaited_once_with(external)
+    assert manager.adopted == set()
+
+
+def test_fault_injection_wraps_docker_adaptor(mock_docker_adaptor):
+    """FAULT_INJECTION_ENABLED 時は DockerAdaptor を障害注入ラッパーで包む"""
+    from services.orchestrator.faults import FaultInjectingAdaptor
+
+    with patch("services.orchestrator.service.config") as mock_config:
+        mock_config.FAULT_INJECTION_ENABLED = True
+        mock_config.FAULT_INJECTION_RULES = [{"operation": "run_container", "action": "fail"}]
+        mock_config.FAULT_INJECTION_SEED = 7
+        mock_config.ADMISSION_CONTROL_ENABLED = False
+        mock_config.CONTAINERS_NETWORK = "test-net"
+        manager = ContainerOrchestrator(network="test-net")
+
+    assert isinstance(manager.docker, FaultInjectingAdaptor)
+    assert manager.docker._adaptor is mock_docker_adaptor
+
+
+@pytest.mark.asyncio
+async def test_collect_memory_stats_ignores_host_memory_as_limit(mock_docker_adaptor):
+    """メモリ上限のないコンテナでは stats の limit (ホストのメモリ量) を記録しない"""
+    mb = 1024 * 1024
+
+    def container(name, memory_limit):
+        c = MagicMock()
+        c.name = name
+        c.labels = {FUNCTION_LABEL: name}
+        c.attrs = {"HostConfig": {"Memory": memory_limit}}
+        return c
+
+    mock_docker_adaptor.list_containers = AsyncMock(
+        return_value=[container("unlimited", 0), container("limited", 256 * mb)]
+    )
+    host_memory = 16 * 1024 * mb
+    mock_docker_adaptor.get_container_stats = AsyncMock(
+        side_effect=lambda c: {
+            "memory_stats": {
+                "usage": 100 * mb,
+                "limit": c.attrs["HostConfig"]["Memory"] or host_memory,
+            }
+        }
+    )
+
+    manager = ContainerOrchestrator(network="test-net")
+    await manager.collect_memory_stats()
+
+    stats = manager.resource_monitor.functions
+    assert stats["unlimited"].memory_limit_bytes == 0
+    assert stats["limited"].memory_limit_bytes == 256 * mb
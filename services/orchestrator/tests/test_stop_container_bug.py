@@ -47,7 +47,7 @@ class TestContainerOrchestratorStopBug:
 
         # 2. stop_container MUST receive the object, not the ID
         # If the bug exists, this will likely fail or the mock call will rely on implementation details
-        mock_docker.stop_container.assert_awaited_with(mock_container_obj)
+        mock_docker.stop_container.assert_awaited_with(mock_container_obj, timeout=None)
 
         # 3. Cleanup verification
         assert container_id not in orchestrator.last_accessed
@@ -0,0 +1,63 @@
+"""
+Tests for function volumes (永続ボリュームとソフトクォータ)
+"""
+
+import pytest
+
+from services.orchestrator.volumes import (
+    VolumeQuotaExceededError,
+    VolumeQuotaTracker,
+    volume_directory_name,
+    volume_driver_opts,
+    volume_name,
+)
+
+MB = 1024 * 1024
+
+
+def test_volume_name_sanitizes_function_name():
+    assert volume_name("lambda-hello") == "esb-data-lambda-hello"
+    assert volume_name("my func/v1") == "esb-data-my_func_v1"
+
+
+def test_volume_driver_opts_binds_under_root():
+    assert volume_driver_opts("hello", None) == {}
+    assert volume_driver_opts("hello", "/data/functions") == {
+        "type": "none",
+        "o": "bind",
+        "device": "/data/functions/hello",
+    }
+
+
+def test_volume_directory_name_stays_under_root():
+    """パス区切りは置換され、ルート外を指す名前は拒否される"""
+    assert volume_directory_name("team/hello") == "team_hello"
+    assert volume_directory_name("../etc") == ".._etc"
+    assert volume_driver_opts("../etc", "/data/functions")["device"] == "/data/functions/.._etc"
+
+    for name in ("", ".", ".."):
+        with pytest.raises(ValueError):
+            volume_directory_name(name)
+
+
+def test_quota_check():
+    tracker = VolumeQuotaTracker()
+    tracker.set_quota("hello", 100)
+
+    tracker.record_usage("hello", 50 * MB)
+    tracker.check("hello")
+
+    tracker.record_usage("hello", 150 * MB)
+    with pytest.raises(VolumeQuotaExceededError) as exc_info:
+        tracker.check("hello")
+    assert exc_info.value.quota_bytes == 100 * MB
+
+
+def test_quota_removed_when_size_not_set():
+    tracker = VolumeQuotaTracker()
+    tracker.set_quota("hello", 100)
+    tracker.record_usage("hello", 150 * MB)
+
+    tracker.set_quota("hello", None)
+    tracker.check("hello")
+    assert tracker.snapshot()["hello"]["quota_bytes"] is None
@@ -0,0 +1,113 @@
+"""
+Function Volumes
+
+関数ごとの永続スクラッチボリュームを管理します。
+ボリュームはコンテナの再作成後も保持され、ローカルキャッシュや SQLite などの状態を残せます。
+
+容量上限 (size_mb) は定期的に計測した使用量と比較するソフトクォータです。
+上限を超えた関数は、使用量が下がるかボリュームが整理されるまで新規コンテナを起動しません。
+"""
+
+import logging
+import os
+import re
+from typing import Dict, Optional
+
+logger = logging.getLogger("orchestrator.volumes")
+
+# ボリューム名の接頭辞
+VOLUME_PREFIX = "esb-data-"
+# 関数名を保持するラベル (使用量計測の対象判定にも使用)
+VOLUME_FUNCTION_LABEL = "esb.function"
+# クォータ (MB) を保持するラベル
+VOLUME_QUOTA_LABEL = "esb.quota_mb"
+
+
+class VolumeQuotaExceededError(Exception):
+    """ボリューム使用量がクォータを超えている関数に対する起動要求の例外"""
+
+    def __init__(self, function_name: str, usage_bytes: int, quota_bytes: int):
+        self.function_name = function_name
+        self.usage_bytes = usage_bytes
+        self.quota_bytes = quota_bytes
+        super().__init__(
+            f"Volume for {function_name} exceeds its quota "
+            f"({usage_bytes // (1024 * 1024)}MB / {quota_bytes // (1024 * 1024)}MB)"
+        )
+
+
+def _sanitize(function_name: str) -> str:
+    """Docker ボリューム名・ディレクトリ名に使用できない文字を '_' に置換"""
+    return re.sub(r"[^a-zA-Z0-9_.-]", "_", function_name)
+
+
+def volume_name(function_name: str) -> str:
+    """関数名から Docker ボリューム名を生成（使用できない文字は '_' に置換）"""
+    return VOLUME_PREFIX + _sanitize(function_name)
+
+
+def volume_directory_name(function_name: str) -> str:
+    """
+    関数名から VOLUME_ROOT 配下のディレクトリ名を生成
+
+    '/' など使用できない文字は '_' に置換するため、結果は常に単一のパス要素になります。
+    ルート自身や親ディレクトリを指す名前 ('.' / '..' など) は ValueError とします。
+    """
+    directory = _sanitize(function_name)
+    if not directory.strip("."):
+        raise ValueError(f"Function name cannot be used as a volume directory: {function_name!r}")
+    return directory
+
+
+def volume_driver_opts(function_name: str, root: Optional[str]) -> Dict[str, str]:
+    """
+    root が指定されている場合、{root}/{ディレクトリ名} をバインドするドライバオプション
+
+    root は Docker デーモンから見たパスです。未指定の場合は Docker のデフォルト保存先を使用します。
+    """
+    if not root:
+        return {}
+    device = os.path.join(root, volume_directory_name(function_name))
+    return {"type": "none", "o": "bind", "device": device}
+
+
+class VolumeQuotaTracker:
+    """
+    関数ごとのクォータと計測済み使用量を保持する
+    """
+
+    def __init__(self):
+        self.quotas: Dict[str, int] = {}
+        self.usage: Dict[str, int] = {}
+
+    def set_quota(self, function_name: str, size_mb: Optional[int]) -> None:
+        if size_mb:
+            self.quotas[function_name] = size_mb * 1024 * 1024
+        else:
+            self.quotas.pop(function_name, None)
+
+    def record_usage(self, function_name: str, usage_bytes: int) -> None:
+        self.usage[function_name] = usage_bytes
+        quota = self.quotas.get(function_name)
+        if quota and usage_bytes > quota:
+            logger.warning(
+                f"Volume for {function_name} exceeds quota ({usage_bytes} / {quota} bytes)"
+            )
+
+    def check(self, function_name: str) -> None:
+        """クォータ超過中であれば VolumeQuotaExceededError を送出"""
+        quota = self.quotas.get(function_name)
+        usage = self.usage.get(function_name, 0)
+        if quota and usage > quota:
+            raise VolumeQuotaExceededError(function_name, usage, quota)
+
+    def snapshot(self) -> Dict[str, Dict[str, Optional[int]]]:
+        """関数ごとの使用量とクォータ"""
+        return {
+            name: {
+                "volume": volume_name(name),
+                "usage_bytes": self.usage.get(name),
+                "quota_bytes": self.quotas.get(name),
+            }
+            for name in sorted(set(self.quotas) | set(self.usage))
+        }
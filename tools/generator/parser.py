@@ -125,6 +125,12 @@ def parse_sam_template(content: str, parameters: dict | None = None) -> dict:
         if min_capacity is not None:
             scaling_config["min_capacity"] = min_capacity
 
+        # --- Phase 1.6: FileSystemConfigs (EFS) -> 関数ごとの永続ボリューム ---
+        volume = None
+        fs_configs = props.get("FileSystemConfigs") or []
+        if fs_configs and fs_configs[0].get("LocalMountPath"):
+            volume = {"path": _resolve_intrinsic(fs_configs[0]["LocalMountPath"], parameters)}
+
         functions.append(
             {
                 "logical_id": logical_id,
@@ -137,6 +143,7 @@ def parse_sam_template(content: str, parameters: dict | None = None) -> dict:
                 "environment": resolved_env,
                 "events": api_routes,
                 "scaling": scaling_config,
+                "volume": volume,
             }
         )
 
@@ -158,6 +158,26 @@ Resources:
         assert func["scaling"]["max_capacity"] == 5
         assert func["scaling"]["min_capacity"] == 2
 
+    def test_parse_function_with_file_system_config(self):
+        """FileSystemConfigs の LocalMountPath を永続ボリュームのマウント先としてパースできる"""
+        sam_content = """
+AWSTemplateFormatVersion: '2010-09-09'
+Transform: AWS::Serverless-2016-10-31
+
+Resources:
+  CacheFunction:
+    Type: AWS::Serverless::Function
+    Properties:
+      FunctionName: lambda-cache
+      FileSystemConfigs:
+        - Arn: arn:aws:elasticfilesystem:ap-northeast-1:123456789012:access-point/fsap-1
+          LocalMountPath: /mnt/cache
+"""
+        result = parse_sam_template(sam_content)
+
+        func = result["functions"][0]
+        assert func["volume"] == {"path": "/mnt/cache"}
+
     def test_parse_resources(self):
         """DynamoDBとS3リソースをパースできる"""
         sam_content = """
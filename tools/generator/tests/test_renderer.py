@@ -95,6 +95,21 @@ class TestFunctionsYmlRenderer:
         assert "max_capacity: 5" in result
         assert "min_capacity: 1" in result
 
+    def test_render_functions_yml_with_volume(self):
+        """関数ごとの永続ボリューム設定を含むfunctions.ymlを生成できる"""
+        functions = [
+            {
+                "name": "lambda-cache",
+                "environment": {},
+                "volume": {"path": "/mnt/cache"},
+            },
+        ]
+
+        result = render_functions_yml(functions)
+
+        assert "volume:" in result
+        assert 'path: "/mnt/cache"' in result
+
     def test_render_routing_yml(self):
         """routing.yml を生成できる"""
         from tools.generator.renderer import render_routing_yml